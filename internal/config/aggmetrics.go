@@ -0,0 +1,63 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"gator/internal/metrics"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// aggMetrics are the Prometheus metrics the aggregator records.
+type aggMetrics struct {
+	registry      *metrics.Registry
+	fetches       *metrics.CounterVec
+	fetchDuration *metrics.Histogram
+	postsInserted *metrics.Counter
+	postsUpdated  *metrics.Counter
+	postsSkipped  *metrics.Counter
+	postsPruned   *metrics.Counter
+	alertsRaised  *metrics.Counter
+	alertErrors   *metrics.Counter
+	feedsDue      *metrics.Gauge
+	dbErrors      *metrics.CounterVec
+}
+
+func newAggMetrics() *aggMetrics {
+	r := metrics.NewRegistry()
+	return &aggMetrics{
+		registry:      r,
+		fetches:       r.NewCounterVec("gator_feed_fetches_total", "Feed fetches by outcome.", "status"),
+		fetchDuration: r.NewHistogram("gator_feed_fetch_duration_seconds", "Time taken to fetch and store a feed.", metrics.DefaultBuckets),
+		postsInserted: r.NewCounter("gator_posts_inserted_total", "Posts stored."),
+		postsUpdated:  r.NewCounter("gator_posts_updated_total", "Stored posts updated because the publisher changed them."),
+		postsSkipped:  r.NewCounter("gator_posts_duplicate_total", "Posts skipped because they were already stored unchanged."),
+		postsPruned:   r.NewCounter("gator_posts_pruned_total", "Posts deleted by the retention policy."),
+		alertsRaised:  r.NewCounter("gator_alerts_raised_total", "Alerts raised by new posts matching alert rules."),
+		alertErrors:   r.NewCounter("gator_alert_delivery_errors_total", "Batches of alerts the notifier failed to deliver."),
+		feedsDue:      r.NewGauge("gator_feeds_due", "Unclaimed feeds due for fetching."),
+		dbErrors:      r.NewCounterVec("gator_db_errors_total", "Database errors by operation.", "op"),
+	}
+}
+
+// serveHTTP starts an HTTP server for handler on addr in the background. The
+// returned function shuts it down.
+func serveHTTP(addr string, handler http.Handler) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Default().Error("http server failed", "addr", addr, "error", err)
+		}
+	}()
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}, nil
+}
@@ -0,0 +1,528 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeedStore is the subset of storage the aggregator needs. dbStore is the
+// postgres implementation, the tests use an in-memory MemStore.
+type FeedStore interface {
+	// WithinTx runs fn against a store whose writes are committed together
+	// if fn returns nil and discarded otherwise.
+	WithinTx(ctx context.Context, fn func(tx FeedStore) error) error
+	ClaimNextFeed(ctx context.Context, arg database.ClaimNextFeedParams) (database.Feed, error)
+	ReleaseFeed(ctx context.Context, arg database.ReleaseFeedParams) error
+	CountDueFeeds(ctx context.Context, dueAfterSeconds float64) (int64, error)
+	RecordHeartbeat(ctx context.Context, arg database.RecordHeartbeatParams) error
+	StopHeartbeat(ctx context.Context, instance string) error
+	DeleteStaleHeartbeats(ctx context.Context, maxAgeSeconds float64) (int64, error)
+	MarkFeedFetched(ctx context.Context, id uuid.UUID) (database.Feed, error)
+	CreatePosts(ctx context.Context, arg database.CreatePostsParams) ([]string, error)
+	UpdateChangedPosts(ctx context.Context, arg database.UpdateChangedPostsParams) ([]string, error)
+	CreateAlerts(ctx context.Context, arg database.CreateAlertsParams) ([]database.CreateAlertsRow, error)
+	pruneStore
+}
+
+// defaultLease is how long a claimed feed stays reserved for one aggregator
+// instance. If the instance dies the claim expires and another picks it up.
+const defaultLease = 2 * time.Minute
+
+type aggregator struct {
+	store    FeedStore
+	fetcher  Fetcher
+	log      *slog.Logger
+	now      func() time.Time
+	instance string
+	lease    time.Duration
+	metrics  *aggMetrics
+	health   aggHealth
+	notifier AlertNotifier
+
+	pruneEvery time.Duration
+	lastPrune  time.Time
+}
+
+func newAggregator(store FeedStore, fetcher Fetcher) *aggregator {
+	if fetcher == nil {
+		fetcher = HTTPFetcher{}
+	}
+	return &aggregator{
+		store:    store,
+		fetcher:  fetcher,
+		log:      slog.Default(),
+		now:      time.Now,
+		instance: instanceID(),
+		lease:    defaultLease,
+		metrics:  newAggMetrics(),
+	}
+}
+
+// instanceID identifies this process when claiming feeds.
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+func (s *State) aggregator() *aggregator {
+	a := newAggregator(dbStore{Queries: s.Db, db: s.Conn}, s.Fetcher)
+	a.notifier = s.Notifier
+	return a
+}
+
+// defaultGracePeriod is how long an in-flight scrape may keep running after
+// agg has been asked to stop.
+const defaultGracePeriod = 10 * time.Second
+
+func Agg(s *State, cmd Command) error {
+	fs := newFlagSet("agg")
+	grace := fs.Duration("grace", defaultGracePeriod, "time allowed for in-flight fetches to finish on shutdown")
+	once := fs.Bool("once", false, "fetch every due feed once and exit")
+	all := fs.Bool("all", false, "with --once, fetch every feed regardless of when it was last fetched")
+	concurrency := fs.Int("concurrency", defaultConcurrency, "with --once, number of feeds fetched at a time")
+	lease := fs.Duration("lease", defaultLease, "how long a claimed feed is reserved for this instance")
+	httpAddr := fs.String("http-addr", "", "address to serve /metrics, /healthz and /readyz on, e.g. :9090")
+	pruneEvery := fs.Duration("prune-every", 0, "delete posts outside the retention policy this often, 0 to disable")
+	readyIntervals := fs.Int("ready-intervals", defaultReadyIntervals, "intervals without a successful scrape before /readyz fails")
+	alertCommand := fs.String("alert-command", "", "shell command run for each alert raised, with the alert as JSON on stdin")
+	alertTimeout := fs.Duration("alert-timeout", defaultAlertTimeout, "time --alert-command may run for one alert before it is killed")
+	args, err := parseArgs(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if *all && !*once {
+		return errors.New("--all can only be used with --once")
+	}
+	if *lease <= *grace {
+		return fmt.Errorf("--lease (%v) must be longer than --grace (%v)", *lease, *grace)
+	}
+	if *pruneEvery > 0 {
+		// pruning deletes every user's posts
+		user, err := s.CurrentUser()
+		if err != nil {
+			return fmt.Errorf("--prune-every needs an admin: %w", err)
+		}
+		if err := RequireAdmin(user); err != nil {
+			return err
+		}
+	}
+	agg := s.aggregator()
+	agg.lease = *lease
+	agg.health.readyIntervals = *readyIntervals
+	if *alertCommand != "" {
+		agg.notifier = CommandNotifier{Command: *alertCommand, Timeout: *alertTimeout}
+	}
+	agg.pruneEvery = *pruneEvery
+	if *httpAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", agg.metrics.registry)
+		mux.Handle("/healthz", healthzHandler(s.Conn))
+		mux.Handle("/readyz", agg.readyzHandler())
+		shutdown, err := serveHTTP(*httpAddr, mux)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", *httpAddr, err)
+		}
+		defer shutdown()
+		slog.Info("serving metrics and health checks", "addr", *httpAddr)
+	}
+	if *once && *all {
+		return agg.runOnce(s.Context(), 0, *concurrency)
+	}
+	if len(args) < 1 {
+		return errors.New("usage: agg [--grace 10s] <time_between_reqs> | agg --once [--concurrency N] (<time_between_reqs> | --all)")
+	}
+	parsedDuration, err := time.ParseDuration(args[0])
+	if err != nil {
+		return err
+	}
+	if parsedDuration <= 0 {
+		return fmt.Errorf("time between requests must be positive, got %v", parsedDuration)
+	}
+	if *once {
+		return agg.runOnce(s.Context(), parsedDuration, *concurrency)
+	}
+	slog.Info("collecting feeds", "interval", parsedDuration, "instance", agg.instance)
+
+	stats := agg.run(s.Context(), parsedDuration, *grace)
+	stats.log(slog.Default())
+	return nil
+}
+
+// run scrapes a feed every interval until ctx is cancelled. A scrape that is
+// in progress when ctx is cancelled is given up to grace to complete.
+func (a *aggregator) run(ctx context.Context, interval, grace time.Duration) sessionStats {
+	stats := sessionStats{started: a.now()}
+	a.health.start(interval, false)
+	a.expireHeartbeats(ctx)
+	defer a.stopHeartbeat(ctx)
+
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	stopGrace := context.AfterFunc(ctx, func() {
+		time.AfterFunc(grace, cancelWork)
+	})
+	defer stopGrace()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// every tick fetches the least recently attempted feed however
+		// recently that was, so every unclaimed feed counts as due
+		a.updateFeedsDue(workCtx, 0)
+		r, ok := a.scrapeFeeds(workCtx, 0)
+		if ok {
+			stats.add(r)
+		}
+		// a tick with nothing to claim still counts as progress
+		a.recordHeartbeat(workCtx, interval, !ok || r.err == nil, stats)
+		a.maybePrune(workCtx)
+		select {
+		case <-ctx.Done():
+			stats.finished = a.now()
+			return stats
+		case <-ticker.C:
+		}
+	}
+}
+
+// defaultConcurrency is how many feeds agg --once fetches in parallel.
+const defaultConcurrency = 4
+
+// runOnce scrapes every feed not attempted within dueAfter of the start of the
+// run, then returns. A dueAfter of zero fetches every feed. It fails if any
+// feed could not be scraped.
+func (a *aggregator) runOnce(ctx context.Context, dueAfter time.Duration, concurrency int) error {
+	stats := sessionStats{started: a.now()}
+	a.health.start(dueAfter, true)
+	a.expireHeartbeats(ctx)
+	defer a.stopHeartbeat(ctx)
+	a.log.InfoContext(ctx, "fetching due feeds once", "due_after", dueAfter, "concurrency", concurrency, "instance", a.instance)
+	a.updateFeedsDue(ctx, dueAfter)
+
+	var failed []feedResult
+	results := a.scrapeDue(ctx, stats.started, dueAfter, concurrency)
+	for _, r := range results {
+		stats.add(r)
+		if r.err != nil {
+			failed = append(failed, r)
+		}
+	}
+	stats.finished = a.now()
+	stats.log(a.log)
+	a.recordHeartbeat(ctx, dueAfter, len(failed) == 0, stats)
+
+	if len(failed) > 0 {
+		for _, r := range failed {
+			a.log.ErrorContext(ctx, "feed failed", "feed_id", r.feed.ID, "url", r.feed.Url, "error", r.err)
+		}
+		return fmt.Errorf("%d of %d feeds failed", len(failed), len(results))
+	}
+	return nil
+}
+
+// scrapeDue runs concurrency workers that keep claiming and scraping feeds
+// that were due at start until none are left.
+func (a *aggregator) scrapeDue(ctx context.Context, start time.Time, dueAfter time.Duration, concurrency int) []feedResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		mu      sync.Mutex
+		results []feedResult
+		wg      sync.WaitGroup
+	)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				// measure due from start so feeds attempted during this run
+				// are not claimed again
+				feed, err := a.claimNext(ctx, dueAfter+a.now().Sub(start))
+				if errors.Is(err, sql.ErrNoRows) {
+					return
+				}
+				var r feedResult
+				if err != nil {
+					a.metrics.dbErrors.Inc("claim")
+					r = feedResult{err: fmt.Errorf("failed to claim feed: %w", err)}
+				} else {
+					r = a.scrapeFeed(ctx, feed)
+				}
+				mu.Lock()
+				results = append(results, r)
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// claimNext reserves the least recently attempted feed that is not claimed
+// by another instance and was last attempted more than dueAfter ago,
+// recording this attempt.
+func (a *aggregator) claimNext(ctx context.Context, dueAfter time.Duration) (database.Feed, error) {
+	return a.store.ClaimNextFeed(ctx, database.ClaimNextFeedParams{
+		ClaimedBy:       sql.NullString{String: a.instance, Valid: true},
+		LeaseSeconds:    a.lease.Seconds(),
+		DueAfterSeconds: dueAfter.Seconds(),
+	})
+}
+
+// release gives up the claim on feed. It runs even if ctx has been cancelled
+// so the feed is not left reserved until the lease expires.
+func (a *aggregator) release(ctx context.Context, feed database.Feed) {
+	err := a.store.ReleaseFeed(context.WithoutCancel(ctx), database.ReleaseFeedParams{
+		ID:        feed.ID,
+		ClaimedBy: sql.NullString{String: a.instance, Valid: true},
+	})
+	if err != nil {
+		a.metrics.dbErrors.Inc("release")
+		a.log.WarnContext(ctx, "failed to release feed", "feed_id", feed.ID, "url", feed.Url, "error", err)
+	}
+}
+
+// feedResult is the outcome of scraping a single feed.
+type feedResult struct {
+	feed     database.Feed
+	inserted int
+	updated  int
+	skipped  int
+	alerts   int
+	err      error
+}
+
+// sessionStats summarises an aggregation session.
+type sessionStats struct {
+	started  time.Time
+	finished time.Time
+	fetched  int
+	failed   int
+	inserted int
+	updated  int
+	skipped  int
+}
+
+func (st *sessionStats) add(r feedResult) {
+	if r.err != nil {
+		st.failed++
+		return
+	}
+	st.fetched++
+	st.inserted += r.inserted
+	st.updated += r.updated
+	st.skipped += r.skipped
+}
+
+func (st sessionStats) log(logger *slog.Logger) {
+	logger.Info("aggregator stopped",
+		"duration", st.finished.Sub(st.started).Round(time.Millisecond),
+		"feeds_fetched", st.fetched,
+		"feeds_failed", st.failed,
+		"posts_inserted", st.inserted,
+		"posts_updated", st.updated,
+		"posts_skipped", st.skipped,
+	)
+}
+
+// scrapeFeeds claims and scrapes the next feed last attempted more than
+// dueAfter ago. ok is false when no such feed is left unclaimed by other
+// instances.
+func (a *aggregator) scrapeFeeds(ctx context.Context, dueAfter time.Duration) (result feedResult, ok bool) {
+	nextFeed, err := a.claimNext(ctx, dueAfter)
+	if errors.Is(err, sql.ErrNoRows) {
+		a.log.DebugContext(ctx, "no unclaimed feeds to fetch")
+		return feedResult{}, false
+	}
+	if err != nil {
+		a.metrics.dbErrors.Inc("claim")
+		a.log.ErrorContext(ctx, "failed to get next feed", "error", err)
+		return feedResult{err: err}, true
+	}
+	a.log.DebugContext(ctx, "fetching feed", "feed_id", nextFeed.ID, "url", nextFeed.Url)
+	return a.scrapeFeed(ctx, nextFeed), true
+}
+
+// scrapeFeed fetches a claimed feed and stores its posts in one
+// transaction, marking the feed fetched on commit. The claim is released
+// either way. Claiming recorded the attempt, so a feed that fails goes to the
+// back of the queue instead of being claimed again before the others.
+func (a *aggregator) scrapeFeed(ctx context.Context, feed database.Feed) feedResult {
+	start := a.now()
+	log := a.log.With("feed_id", feed.ID, "url", feed.Url)
+	result := feedResult{feed: feed}
+	defer func() {
+		a.metrics.fetchDuration.Observe(a.now().Sub(start).Seconds())
+		if result.err != nil {
+			a.metrics.fetches.Inc("error")
+			return
+		}
+		a.metrics.fetches.Inc("success")
+		a.metrics.postsInserted.Add(float64(result.inserted))
+		a.metrics.postsUpdated.Add(float64(result.updated))
+		a.metrics.postsSkipped.Add(float64(result.skipped))
+		a.metrics.alertsRaised.Add(float64(result.alerts))
+	}()
+
+	returnedFeed, err := a.fetcher.Fetch(ctx, feed.Url)
+	if err != nil {
+		log.WarnContext(ctx, "failed to fetch feed", "duration", a.now().Sub(start), "error", err)
+		a.release(ctx, feed)
+		result.err = err
+		return result
+	}
+	items := uniqueItems(returnedFeed.Channel.Item)
+
+	var alerts []database.CreateAlertsRow
+	err = a.store.WithinTx(ctx, func(tx FeedStore) error {
+		inserted, err := tx.CreatePosts(ctx, newCreatePostsParams(feed.ID, a.now().UTC(), items))
+		if err != nil {
+			a.metrics.dbErrors.Inc("create_posts")
+			return fmt.Errorf("failed to create posts: %w", err)
+		}
+		if len(inserted) > 0 {
+			alerts, err = tx.CreateAlerts(ctx, database.CreateAlertsParams{FeedID: feed.ID, Urls: inserted})
+			if err != nil {
+				a.metrics.dbErrors.Inc("create_alerts")
+				return fmt.Errorf("failed to record alerts: %w", err)
+			}
+		}
+		// items that were already stored are updated if the publisher has
+		// changed them since
+		existing := slices.DeleteFunc(slices.Clone(items), func(item RSSItem) bool {
+			return slices.Contains(inserted, item.Link)
+		})
+		var updated []string
+		if len(existing) > 0 {
+			updated, err = tx.UpdateChangedPosts(ctx, newUpdateChangedPostsParams(feed.ID, existing))
+			if err != nil {
+				a.metrics.dbErrors.Inc("update_posts")
+				return fmt.Errorf("failed to update posts: %w", err)
+			}
+		}
+		if _, err := tx.MarkFeedFetched(ctx, feed.ID); err != nil {
+			a.metrics.dbErrors.Inc("mark_fetched")
+			return fmt.Errorf("failed to mark feed fetched: %w", err)
+		}
+		result.inserted = len(inserted)
+		result.updated = len(updated)
+		result.skipped = len(items) - len(inserted) - len(updated)
+		result.alerts = len(alerts)
+		return nil
+	})
+	if err != nil {
+		log.ErrorContext(ctx, "failed to store feed", "duration", a.now().Sub(start), "error", err)
+		a.release(ctx, feed)
+		result = feedResult{feed: feed, err: err}
+		return result
+	}
+	a.release(ctx, feed)
+	// only deliver alerts once they are committed
+	a.deliverAlerts(ctx, alerts)
+
+	log.InfoContext(ctx, "feed collected",
+		"feed", feed.Name,
+		"items", len(returnedFeed.Channel.Item),
+		"inserted", result.inserted,
+		"updated", result.updated,
+		"skipped", result.skipped,
+		"alerts", result.alerts,
+		"duration", a.now().Sub(start),
+	)
+	return result
+}
+
+// uniqueItems drops items whose link appeared earlier in the feed, since a
+// batch may only touch each post once.
+func uniqueItems(items []RSSItem) []RSSItem {
+	seen := make(map[string]bool, len(items))
+	unique := make([]RSSItem, 0, len(items))
+	for _, item := range items {
+		if seen[item.Link] {
+			continue
+		}
+		seen[item.Link] = true
+		unique = append(unique, item)
+	}
+	return unique
+}
+
+func newCreatePostsParams(feedID uuid.UUID, now time.Time, items []RSSItem) database.CreatePostsParams {
+	arg := database.CreatePostsParams{CreatedAt: now, FeedID: feedID}
+	for _, item := range items {
+		publishedAt := ""
+		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+			publishedAt = t.UTC().Format(time.DateTime)
+		}
+		arg.Ids = append(arg.Ids, uuid.New())
+		arg.Titles = append(arg.Titles, item.Title)
+		arg.Urls = append(arg.Urls, item.Link)
+		arg.Descriptions = append(arg.Descriptions, item.Description)
+		arg.PublishedAts = append(arg.PublishedAts, publishedAt)
+		arg.Contents = append(arg.Contents, item.Content)
+		arg.Authors = append(arg.Authors, item.author())
+	}
+	return arg
+}
+
+func newUpdateChangedPostsParams(feedID uuid.UUID, items []RSSItem) database.UpdateChangedPostsParams {
+	arg := database.UpdateChangedPostsParams{FeedID: feedID}
+	for _, item := range items {
+		arg.Urls = append(arg.Urls, item.Link)
+		arg.Titles = append(arg.Titles, item.Title)
+		arg.Descriptions = append(arg.Descriptions, item.Description)
+		arg.Contents = append(arg.Contents, item.Content)
+	}
+	return arg
+}
+
+// maybePrune applies the retention policy if pruning is enabled and
+// pruneEvery has passed since the last prune.
+func (a *aggregator) maybePrune(ctx context.Context) {
+	if a.pruneEvery <= 0 || a.now().Sub(a.lastPrune) < a.pruneEvery {
+		return
+	}
+	a.lastPrune = a.now()
+	counts, err := prune(ctx, a.store)
+	if err != nil {
+		a.metrics.dbErrors.Inc("prune")
+		a.log.ErrorContext(ctx, "failed to prune posts", "error", err)
+		return
+	}
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	a.metrics.postsPruned.Add(float64(total))
+	a.log.InfoContext(ctx, "pruned posts", "deleted", total, "feeds", len(counts))
+}
+
+// updateFeedsDue records how many unclaimed feeds were last attempted more
+// than dueAfter ago.
+func (a *aggregator) updateFeedsDue(ctx context.Context, dueAfter time.Duration) {
+	n, err := a.store.CountDueFeeds(ctx, dueAfter.Seconds())
+	if err != nil {
+		a.metrics.dbErrors.Inc("count_due")
+		a.log.WarnContext(ctx, "failed to count due feeds", "error", err)
+		return
+	}
+	a.metrics.feedsDue.Set(float64(n))
+}
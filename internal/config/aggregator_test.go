@@ -0,0 +1,502 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gator/internal/database"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testClock is a settable clock shared by an aggregator and its MemStore.
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time { return c.t }
+
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestAggregator() (*aggregator, *MemStore, *MemFetcher, *testClock) {
+	clock := &testClock{t: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	store := &MemStore{Now: clock.now}
+	fetcher := &MemFetcher{Feeds: map[string]*RSSFeed{}, Errors: map[string]error{}}
+	agg := newAggregator(store, fetcher)
+	agg.now = clock.now
+	agg.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return agg, store, fetcher, clock
+}
+
+func rssFeed(items ...RSSItem) *RSSFeed {
+	feed := &RSSFeed{}
+	feed.Channel.Item = items
+	return feed
+}
+
+func item(title, link string) RSSItem {
+	return RSSItem{Title: title, Link: link, Description: title + " description"}
+}
+
+func withContent(item RSSItem, content string) RSSItem {
+	item.Content = content
+	return item
+}
+
+func TestClaimNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		fetched  []time.Duration // how long ago each feed was fetched, 0 for never
+		claimed  []bool
+		dueAfter time.Duration
+		want     int // index of the claimed feed, -1 for none
+	}{
+		{"never fetched first", []time.Duration{time.Hour, 0, 2 * time.Hour}, nil, 0, 1},
+		{"least recently fetched", []time.Duration{time.Hour, 3 * time.Hour, 2 * time.Hour}, nil, 0, 1},
+		{"skips claimed feeds", []time.Duration{time.Hour, 3 * time.Hour}, []bool{false, true}, 0, 0},
+		{"all claimed", []time.Duration{time.Hour}, []bool{true}, 0, -1},
+		{"skips feeds fetched within dueAfter", []time.Duration{10 * time.Minute, 40 * time.Minute}, nil, 30 * time.Minute, 1},
+		{"nothing due", []time.Duration{10 * time.Minute}, nil, 30 * time.Minute, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg, store, _, clock := newTestAggregator()
+			for i, ago := range tt.fetched {
+				store.AddFeed("feed", "https://example.com/"+uuid.NewString())
+				if ago > 0 {
+					store.SetFetched(i, clock.now().Add(-ago))
+				}
+				if i < len(tt.claimed) && tt.claimed[i] {
+					store.Feeds[i].ClaimedBy = sql.NullString{String: "other", Valid: true}
+					store.Feeds[i].ClaimedUntil = sql.NullTime{Time: clock.now().Add(time.Minute), Valid: true}
+				}
+			}
+			feed, err := agg.claimNext(context.Background(), tt.dueAfter)
+			if tt.want == -1 {
+				if !errors.Is(err, sql.ErrNoRows) {
+					t.Fatalf("claimNext() = %v, %v, want sql.ErrNoRows", feed.Url, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("claimNext() error: %v", err)
+			}
+			if feed.ID != store.Feeds[tt.want].ID {
+				t.Errorf("claimed %s, want %s", feed.Url, store.Feeds[tt.want].Url)
+			}
+			if !store.Feeds[tt.want].ClaimedBy.Valid || store.Feeds[tt.want].ClaimedBy.String != agg.instance {
+				t.Errorf("feed not claimed by this instance: %+v", store.Feeds[tt.want].ClaimedBy)
+			}
+		})
+	}
+}
+
+func TestClaimExpires(t *testing.T) {
+	agg, store, _, clock := newTestAggregator()
+	store.AddFeed("feed", "https://example.com/feed")
+	if _, err := agg.claimNext(context.Background(), 0); err != nil {
+		t.Fatalf("first claim: %v", err)
+	}
+	if _, err := agg.claimNext(context.Background(), 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("second claim within the lease = %v, want sql.ErrNoRows", err)
+	}
+	clock.advance(agg.lease + time.Second)
+	if _, err := agg.claimNext(context.Background(), 0); err != nil {
+		t.Fatalf("claim after the lease expired: %v", err)
+	}
+}
+
+func TestScrapeFeed(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second []RSSItem
+		want          feedResult
+		wantPosts     int
+		wantRevisions int
+	}{
+		{
+			name:      "new posts",
+			second:    []RSSItem{item("a", "https://example.com/a"), item("b", "https://example.com/b")},
+			want:      feedResult{inserted: 2},
+			wantPosts: 2,
+		},
+		{
+			name:      "duplicate links in one fetch",
+			second:    []RSSItem{item("a", "https://example.com/a"), item("a again", "https://example.com/a")},
+			want:      feedResult{inserted: 1},
+			wantPosts: 1,
+		},
+		{
+			name:      "unchanged posts are skipped",
+			first:     []RSSItem{item("a", "https://example.com/a")},
+			second:    []RSSItem{item("a", "https://example.com/a"), item("b", "https://example.com/b")},
+			want:      feedResult{inserted: 1, skipped: 1},
+			wantPosts: 2,
+		},
+		{
+			name:          "changed posts are updated with a revision",
+			first:         []RSSItem{item("a", "https://example.com/a")},
+			second:        []RSSItem{item("a, corrected", "https://example.com/a")},
+			want:          feedResult{updated: 1},
+			wantPosts:     1,
+			wantRevisions: 1,
+		},
+		{
+			name:      "content on a post stored without any is filled in",
+			first:     []RSSItem{item("a", "https://example.com/a")},
+			second:    []RSSItem{withContent(item("a", "https://example.com/a"), "body")},
+			want:      feedResult{skipped: 1},
+			wantPosts: 1,
+		},
+		{
+			name:          "changed content is updated with a revision",
+			first:         []RSSItem{withContent(item("a", "https://example.com/a"), "body")},
+			second:        []RSSItem{withContent(item("a", "https://example.com/a"), "new body")},
+			want:          feedResult{updated: 1},
+			wantPosts:     1,
+			wantRevisions: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg, store, fetcher, clock := newTestAggregator()
+			feed := store.AddFeed("feed", "https://example.com/feed")
+			if tt.first != nil {
+				fetcher.Feeds[feed.Url] = rssFeed(tt.first...)
+				if r := agg.scrapeFeed(context.Background(), feed); r.err != nil {
+					t.Fatalf("first scrape: %v", r.err)
+				}
+				clock.advance(time.Minute)
+			}
+			fetcher.Feeds[feed.Url] = rssFeed(tt.second...)
+			r := agg.scrapeFeed(context.Background(), feed)
+			if r.err != nil {
+				t.Fatalf("scrapeFeed() error: %v", r.err)
+			}
+			if r.inserted != tt.want.inserted || r.updated != tt.want.updated || r.skipped != tt.want.skipped {
+				t.Errorf("scrapeFeed() inserted/updated/skipped = %d/%d/%d, want %d/%d/%d",
+					r.inserted, r.updated, r.skipped, tt.want.inserted, tt.want.updated, tt.want.skipped)
+			}
+			if len(store.Posts) != tt.wantPosts {
+				t.Errorf("%d posts stored, want %d", len(store.Posts), tt.wantPosts)
+			}
+			if len(store.Revisions) != tt.wantRevisions {
+				t.Errorf("%d revisions stored, want %d", len(store.Revisions), tt.wantRevisions)
+			}
+			if !store.Feeds[0].LastFetchedAt.Valid || !store.Feeds[0].LastFetchedAt.Time.Equal(clock.now()) {
+				t.Errorf("feed not marked fetched: %+v", store.Feeds[0].LastFetchedAt)
+			}
+		})
+	}
+}
+
+func TestScrapeFeedRevisionKeepsOldVersion(t *testing.T) {
+	agg, store, fetcher, _ := newTestAggregator()
+	feed := store.AddFeed("feed", "https://example.com/feed")
+	fetcher.Feeds[feed.Url] = rssFeed(item("first title", "https://example.com/a"))
+	agg.scrapeFeed(context.Background(), feed)
+	fetcher.Feeds[feed.Url] = rssFeed(item("second title", "https://example.com/a"))
+	agg.scrapeFeed(context.Background(), feed)
+	if len(store.Revisions) != 1 || store.Revisions[0].Title != "first title" {
+		t.Fatalf("revisions = %+v, want one with the first title", store.Revisions)
+	}
+	if store.Posts[0].Title != "second title" {
+		t.Errorf("post title = %q, want the second title", store.Posts[0].Title)
+	}
+}
+
+func TestScrapeFeedAdoptsOrphans(t *testing.T) {
+	tests := []struct {
+		name      string
+		feedURL   string
+		wantOwned bool
+	}{
+		{"same feed URL", "https://example.com/feed", true},
+		{"other feed", "https://other.example.com/feed", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg, store, fetcher, _ := newTestAggregator()
+			old := store.AddFeed("old", "https://example.com/feed")
+			fetcher.Feeds[old.Url] = rssFeed(item("a", "https://example.com/a"))
+			agg.scrapeFeed(context.Background(), old)
+			// the feed was deleted, keeping its starred post
+			store.Feeds = nil
+			store.Posts[0].FeedID = uuid.NullUUID{}
+
+			feed := store.AddFeed("new", tt.feedURL)
+			fetcher.Feeds[feed.Url] = rssFeed(item("a", "https://example.com/a"))
+			r := agg.scrapeFeed(context.Background(), feed)
+			if r.err != nil {
+				t.Fatalf("scrapeFeed() error: %v", r.err)
+			}
+			owned := store.Posts[0].FeedID.Valid && store.Posts[0].FeedID.UUID == feed.ID
+			if owned != tt.wantOwned {
+				t.Errorf("post adopted = %t, want %t", owned, tt.wantOwned)
+			}
+		})
+	}
+}
+
+func TestScrapeFeedErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(store *MemStore, fetcher *MemFetcher, feed database.Feed)
+		wantErr string
+	}{
+		{
+			name: "fetch fails",
+			setup: func(store *MemStore, fetcher *MemFetcher, feed database.Feed) {
+				fetcher.Errors[feed.Url] = errors.New("connection refused")
+			},
+			wantErr: "connection refused",
+		},
+		{
+			name:    "feed not found",
+			setup:   func(store *MemStore, fetcher *MemFetcher, feed database.Feed) {},
+			wantErr: "unexpected status code: 404",
+		},
+		{
+			name: "storing fails and is rolled back",
+			setup: func(store *MemStore, fetcher *MemFetcher, feed database.Feed) {
+				fetcher.Feeds[feed.Url] = rssFeed(item("a", "https://example.com/a"))
+				store.AlertErr = errors.New("database is down")
+			},
+			wantErr: "database is down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg, store, fetcher, _ := newTestAggregator()
+			store.AddFeed("feed", "https://example.com/feed")
+			feed, err := agg.claimNext(context.Background(), 0)
+			if err != nil {
+				t.Fatalf("claimNext() error: %v", err)
+			}
+			tt.setup(store, fetcher, feed)
+			r := agg.scrapeFeed(context.Background(), feed)
+			if r.err == nil || !strings.Contains(r.err.Error(), tt.wantErr) {
+				t.Fatalf("scrapeFeed() error = %v, want %q", r.err, tt.wantErr)
+			}
+			if len(store.Posts) != 0 {
+				t.Errorf("%d posts stored after a failed scrape, want 0", len(store.Posts))
+			}
+			if store.Feeds[0].LastFetchedAt.Valid {
+				t.Error("feed marked fetched after a failed scrape")
+			}
+			// the attempt is recorded and the claim released so the feed is
+			// retried after the others
+			if store.Feeds[0].ClaimedUntil.Valid {
+				t.Error("claim kept after a failed scrape")
+			}
+			if !store.Feeds[0].LastAttemptAt.Valid {
+				t.Error("attempt not recorded after a failed scrape")
+			}
+		})
+	}
+}
+
+func TestFailingFeedsDontStarveOthers(t *testing.T) {
+	agg, store, fetcher, clock := newTestAggregator()
+	var healthy string
+	for i := range 3 {
+		feed := store.AddFeed("feed", "https://example.com/"+uuid.NewString())
+		if i < 2 {
+			fetcher.Errors[feed.Url] = errors.New("boom")
+		} else {
+			fetcher.Feeds[feed.Url] = rssFeed()
+			healthy = feed.Url
+			store.SetFetched(i, clock.now().Add(-time.Minute))
+		}
+	}
+	// ticks of run, each within the lease of the previous ones
+	for range 6 {
+		clock.advance(time.Second)
+		if _, ok := agg.scrapeFeeds(context.Background(), 0); !ok {
+			t.Fatal("no feed to claim")
+		}
+	}
+	if n := slices.Index(fetcher.Calls, healthy); n == -1 || n > 2 {
+		t.Errorf("healthy feed not fetched in the first round: %v", fetcher.Calls)
+	}
+	if got := len(slices.DeleteFunc(slices.Clone(fetcher.Calls), func(url string) bool { return url != healthy })); got != 2 {
+		t.Errorf("healthy feed fetched %d times in 6 ticks, want 2: %v", got, fetcher.Calls)
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	tests := []struct {
+		name        string
+		feeds       int
+		failing     int
+		fetchedAgo  time.Duration
+		dueAfter    time.Duration
+		wantFetched int
+		wantErr     bool
+	}{
+		{"every feed", 5, 0, 0, 0, 5, false},
+		{"only due feeds", 3, 0, 10 * time.Minute, 30 * time.Minute, 0, false},
+		{"due feeds", 3, 0, time.Hour, 30 * time.Minute, 3, false},
+		{"a failing feed fails the run", 3, 1, 0, 0, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg, store, fetcher, clock := newTestAggregator()
+			for i := range tt.feeds {
+				feed := store.AddFeed("feed", "https://example.com/"+uuid.NewString())
+				if tt.fetchedAgo > 0 {
+					store.SetFetched(i, clock.now().Add(-tt.fetchedAgo))
+				}
+				if i < tt.failing {
+					fetcher.Errors[feed.Url] = errors.New("boom")
+				} else {
+					fetcher.Feeds[feed.Url] = rssFeed(item("a", feed.Url+"/a"))
+				}
+			}
+			err := agg.runOnce(context.Background(), tt.dueAfter, 2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runOnce() error = %v, want error %t", err, tt.wantErr)
+			}
+			if len(fetcher.Calls) != tt.wantFetched {
+				t.Errorf("fetched %d feeds, want %d", len(fetcher.Calls), tt.wantFetched)
+			}
+			slices.Sort(fetcher.Calls)
+			if len(slices.Compact(fetcher.Calls)) != len(fetcher.Calls) {
+				t.Errorf("a feed was fetched more than once: %v", fetcher.Calls)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    database.DefaultRetention
+		override  *database.FeedRetention
+		starred   bool
+		unread    bool
+		orphaned  bool
+		wantPosts int
+	}{
+		{"unlimited keeps everything", database.DefaultRetention{}, nil, false, false, false, 4},
+		{"max posts", database.DefaultRetention{MaxPosts: 2}, nil, false, false, false, 2},
+		{"max age", database.DefaultRetention{MaxAgeSeconds: 2 * 24 * 60 * 60}, nil, false, false, false, 2},
+		{"starred posts are kept", database.DefaultRetention{MaxPosts: 1}, nil, true, false, false, 2},
+		{"unread posts are kept", database.DefaultRetention{MaxPosts: 1, KeepUnread: true}, nil, false, true, false, 4},
+		{"feed override", database.DefaultRetention{MaxPosts: 1}, &database.FeedRetention{MaxPosts: sql.NullInt32{Int32: 3, Valid: true}}, false, false, false, 3},
+		{"orphaned posts are deleted", database.DefaultRetention{}, nil, false, false, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, store, _, clock := newTestAggregator()
+			feed := store.AddFeed("feed", "https://example.com/feed")
+			// four posts published 1, 2, 3 and 4 days ago, the oldest first
+			for days := 4; days >= 1; days-- {
+				store.Posts = append(store.Posts, database.Post{
+					ID:          uuid.New(),
+					Url:         "https://example.com/" + uuid.NewString(),
+					PublishedAt: sql.NullTime{Time: clock.now().Add(-time.Duration(days) * 24 * time.Hour), Valid: true},
+					FeedID:      uuid.NullUUID{UUID: feed.ID, Valid: true},
+				})
+			}
+			store.Default = tt.policy
+			if tt.override != nil {
+				tt.override.FeedID = feed.ID
+				store.Retention = map[uuid.UUID]database.FeedRetention{feed.ID: *tt.override}
+			}
+			if tt.starred {
+				store.PostStates = append(store.PostStates, database.PostState{PostID: store.Posts[0].ID, StarredAt: sql.NullTime{Time: clock.now(), Valid: true}})
+			}
+			if tt.unread {
+				store.Follows = append(store.Follows, database.FeedFollow{UserID: uuid.New(), FeedID: feed.ID})
+			}
+			if tt.orphaned {
+				store.Posts[0].FeedID = uuid.NullUUID{}
+			}
+			if _, err := prune(context.Background(), store); err != nil {
+				t.Fatalf("prune() error: %v", err)
+			}
+			if len(store.Posts) != tt.wantPosts {
+				t.Errorf("%d posts left, want %d", len(store.Posts), tt.wantPosts)
+			}
+		})
+	}
+}
+
+type recordingNotifier struct {
+	alerts []database.CreateAlertsRow
+	err    error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alerts []database.CreateAlertsRow) error {
+	n.alerts = append(n.alerts, alerts...)
+	return n.err
+}
+
+func TestScrapeFeedDeliversAlerts(t *testing.T) {
+	for _, notifyErr := range []error{nil, errors.New("hook failed")} {
+		agg, store, fetcher, _ := newTestAggregator()
+		feed := store.AddFeed("feed", "https://example.com/feed")
+		fetcher.Feeds[feed.Url] = rssFeed(item("CVE-2024-1234", "https://example.com/a"), item("other", "https://example.com/b"))
+		store.Alerts = map[string][]database.CreateAlertsRow{
+			"https://example.com/a": {{ID: uuid.New(), PostUrl: "https://example.com/a"}},
+		}
+		notifier := &recordingNotifier{err: notifyErr}
+		agg.notifier = notifier
+		r := agg.scrapeFeed(context.Background(), feed)
+		if r.err != nil {
+			t.Fatalf("scrapeFeed() error with notifier error %v: %v", notifyErr, r.err)
+		}
+		if r.alerts != 1 || len(notifier.alerts) != 1 {
+			t.Errorf("alerts = %d, delivered %d, want 1", r.alerts, len(notifier.alerts))
+		}
+	}
+}
+
+func TestCommandNotifierTimeout(t *testing.T) {
+	n := CommandNotifier{Command: "sleep 10", Timeout: 50 * time.Millisecond}
+	alerts := []database.CreateAlertsRow{{ID: uuid.New(), PostUrl: "https://example.com/a"}}
+	start := time.Now()
+	err := n.Notify(context.Background(), alerts)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Notify() error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify() took %v, want it killed after the timeout", elapsed)
+	}
+}
+
+func TestFeedsDueMatchesClaimable(t *testing.T) {
+	agg, store, fetcher, clock := newTestAggregator()
+	for i := range 3 {
+		feed := store.AddFeed("feed", "https://example.com/"+uuid.NewString())
+		fetcher.Feeds[feed.Url] = rssFeed()
+		store.SetFetched(i, clock.now().Add(-time.Minute))
+	}
+	store.Feeds[2].ClaimedBy = sql.NullString{String: "other", Valid: true}
+	store.Feeds[2].ClaimedUntil = sql.NullTime{Time: clock.now().Add(time.Minute), Valid: true}
+
+	// the same steps as a tick of run
+	agg.updateFeedsDue(context.Background(), 0)
+	var b strings.Builder
+	agg.metrics.registry.Write(&b)
+	if !strings.Contains(b.String(), "gator_feeds_due 2\n") {
+		t.Errorf("gauge doesn't report 2 due feeds:\n%s", b.String())
+	}
+	claimed := 0
+	for {
+		if _, ok := agg.scrapeFeeds(context.Background(), 0); !ok {
+			break
+		}
+		claimed++
+	}
+	if claimed != 2 {
+		t.Errorf("scraped %d feeds, want the 2 reported due", claimed)
+	}
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultReadyIntervals is how many intervals may pass without a successful
+// scrape before an aggregator is reported as not ready.
+const defaultReadyIntervals = 3
+
+// heartbeatRetention is how long an instance that stopped reporting is
+// still listed by gator status.
+const heartbeatRetention = 7 * 24 * time.Hour
+
+// aggHealth tracks when the aggregator last made progress. A one-shot run
+// (agg --once) has no interval to be late by, so it is always ready.
+type aggHealth struct {
+	mu             sync.Mutex
+	interval       time.Duration
+	once           bool
+	readyIntervals int
+	lastSuccess    time.Time
+}
+
+func (h *aggHealth) start(interval time.Duration, once bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.interval = interval
+	h.once = once
+	if h.readyIntervals < 1 {
+		h.readyIntervals = defaultReadyIntervals
+	}
+}
+
+func (h *aggHealth) succeeded(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastSuccess = now
+}
+
+func (h *aggHealth) ready(now time.Time) (bool, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.once {
+		return true, "running once"
+	}
+	if h.lastSuccess.IsZero() {
+		return false, "no successful scrape yet"
+	}
+	if !isReady(h.lastSuccess, now, h.interval, h.readyIntervals) {
+		return false, fmt.Sprintf("last successful scrape %v ago", now.Sub(h.lastSuccess).Round(time.Second))
+	}
+	return true, "ok"
+}
+
+// isReady reports whether lastSuccess is within intervals intervals of now.
+func isReady(lastSuccess, now time.Time, interval time.Duration, intervals int) bool {
+	return now.Sub(lastSuccess) <= time.Duration(intervals)*interval
+}
+
+// recordHeartbeat notes the outcome of a tick in memory for /readyz and in
+// the database for gator status.
+func (a *aggregator) recordHeartbeat(ctx context.Context, interval time.Duration, succeeded bool, stats sessionStats) {
+	if succeeded {
+		a.health.succeeded(a.now())
+	}
+	a.health.mu.Lock()
+	once := a.health.once
+	a.health.mu.Unlock()
+	err := a.store.RecordHeartbeat(ctx, database.RecordHeartbeatParams{
+		Instance:        a.instance,
+		IntervalSeconds: interval.Seconds(),
+		Once:            once,
+		Succeeded:       succeeded,
+		FeedsFetched:    int32(stats.fetched),
+		FeedsFailed:     int32(stats.failed),
+	})
+	if err != nil {
+		a.metrics.dbErrors.Inc("heartbeat")
+		a.log.WarnContext(ctx, "failed to record heartbeat", "error", err)
+	}
+}
+
+// expireHeartbeats deletes the heartbeats of instances that have not
+// reported for heartbeatRetention. Every process reports under a new
+// instance ID, so without this they would pile up.
+func (a *aggregator) expireHeartbeats(ctx context.Context) {
+	n, err := a.store.DeleteStaleHeartbeats(ctx, heartbeatRetention.Seconds())
+	if err != nil {
+		a.metrics.dbErrors.Inc("heartbeat")
+		a.log.WarnContext(ctx, "failed to delete old heartbeats", "error", err)
+		return
+	}
+	if n > 0 {
+		a.log.DebugContext(ctx, "deleted old heartbeats", "count", n)
+	}
+}
+
+func (a *aggregator) stopHeartbeat(ctx context.Context) {
+	if err := a.store.StopHeartbeat(context.WithoutCancel(ctx), a.instance); err != nil {
+		a.metrics.dbErrors.Inc("heartbeat")
+		a.log.WarnContext(ctx, "failed to record shutdown", "error", err)
+	}
+}
+
+// healthzHandler reports whether the process is up and can reach the
+// database.
+func healthzHandler(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if db == nil {
+			http.Error(w, "database not configured", http.StatusServiceUnavailable)
+			return
+		}
+		if err := db.PingContext(ctx); err != nil {
+			http.Error(w, fmt.Sprintf("database unreachable: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// readyzHandler reports whether the aggregator has scraped successfully
+// recently enough.
+func (a *aggregator) readyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, reason := a.health.ready(a.now())
+		if !ok {
+			http.Error(w, reason, http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, reason)
+	})
+}
+
+// HandlerStatus prints the aggregator instances that have reported to the
+// database and whether each one is making progress.
+func HandlerStatus(s *State, cmd Command) error {
+	fs := newFlagSet("status")
+	readyIntervals := fs.Int("ready-intervals", defaultReadyIntervals, "intervals without a successful scrape before an instance is not ready")
+	if _, err := parseArgs(fs, cmd.Args); err != nil {
+		return err
+	}
+
+	if err := s.Conn.PingContext(s.Context()); err != nil {
+		return fmt.Errorf("database unreachable: %w", err)
+	}
+	heartbeats, err := s.Db.ListHeartbeats(s.Context())
+	if err != nil {
+		return fmt.Errorf("failed to list aggregator heartbeats: %w", err)
+	}
+	if len(heartbeats) == 0 && s.Output.Text() {
+		fmt.Println("No aggregator has reported yet")
+		return nil
+	}
+
+	anyReady, anyContinuous := false, false
+	records := make([]heartbeatRecord, 0, len(heartbeats))
+	for _, hb := range heartbeats {
+		state := heartbeatState(hb, *readyIntervals)
+		if state == "ready" {
+			anyReady = true
+		}
+		if !hb.Once {
+			anyContinuous = true
+		}
+		if !s.Output.Text() {
+			records = append(records, heartbeatRecord{
+				Instance:        hb.Instance,
+				State:           state,
+				Once:            hb.Once,
+				StartedAt:       hb.StartedAt,
+				LastSeenAt:      hb.LastSeenAt,
+				LastSuccessAt:   nullTime(hb.LastSuccessAt),
+				IntervalSeconds: hb.IntervalSeconds,
+				FeedsFetched:    hb.FeedsFetched,
+				FeedsFailed:     hb.FeedsFailed,
+			})
+			continue
+		}
+		fmt.Printf("%s: %s\n", hb.Instance, state)
+		fmt.Printf("    started %s, last seen %s\n", hb.StartedAt.Format(time.DateTime), ago(hb.DbNow, hb.LastSeenAt))
+		if hb.LastSuccessAt.Valid {
+			fmt.Printf("    last successful scrape %s\n", ago(hb.DbNow, hb.LastSuccessAt.Time))
+		} else {
+			fmt.Println("    no successful scrape yet")
+		}
+		if hb.Once {
+			fmt.Printf("    one-shot run, feeds fetched %d, failed %d\n", hb.FeedsFetched, hb.FeedsFailed)
+		} else {
+			fmt.Printf("    interval %v, feeds fetched %d, failed %d\n", secondsToDuration(hb.IntervalSeconds), hb.FeedsFetched, hb.FeedsFailed)
+		}
+	}
+	if !s.Output.Text() {
+		if err := printRecords(s, records); err != nil {
+			return err
+		}
+	}
+	// one-shot runs have no readiness, only fail if a continuous
+	// aggregator should be running
+	if anyContinuous && !anyReady {
+		return errors.New("no aggregator is ready")
+	}
+	return nil
+}
+
+func heartbeatState(hb database.ListHeartbeatsRow, readyIntervals int) string {
+	interval := secondsToDuration(hb.IntervalSeconds)
+	if hb.Once {
+		if hb.StoppedAt.Valid {
+			return "finished"
+		}
+		return "running once"
+	}
+	switch {
+	case hb.StoppedAt.Valid:
+		return "stopped"
+	case !isReady(hb.LastSeenAt, hb.DbNow, interval, readyIntervals):
+		return "not responding"
+	case !hb.LastSuccessAt.Valid || !isReady(hb.LastSuccessAt.Time, hb.DbNow, interval, readyIntervals):
+		return "not ready"
+	}
+	return "ready"
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func ago(now, t time.Time) string {
+	return fmt.Sprintf("%v ago", now.Sub(t).Round(time.Second))
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"gator/internal/database"
+)
+
+func TestHeartbeatState(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) sql.NullTime { return sql.NullTime{Time: now.Add(-ago), Valid: true} }
+	tests := []struct {
+		name string
+		hb   database.ListHeartbeatsRow
+		want string
+	}{
+		{"ready", database.ListHeartbeatsRow{IntervalSeconds: 30, LastSeenAt: now.Add(-10 * time.Second), LastSuccessAt: at(10 * time.Second)}, "ready"},
+		{"no success yet", database.ListHeartbeatsRow{IntervalSeconds: 30, LastSeenAt: now.Add(-10 * time.Second)}, "not ready"},
+		{"failing", database.ListHeartbeatsRow{IntervalSeconds: 30, LastSeenAt: now.Add(-10 * time.Second), LastSuccessAt: at(5 * time.Minute)}, "not ready"},
+		{"silent", database.ListHeartbeatsRow{IntervalSeconds: 30, LastSeenAt: now.Add(-5 * time.Minute), LastSuccessAt: at(5 * time.Minute)}, "not responding"},
+		{"stopped", database.ListHeartbeatsRow{IntervalSeconds: 30, LastSeenAt: now.Add(-5 * time.Minute), StoppedAt: at(5 * time.Minute)}, "stopped"},
+		{"once --all running", database.ListHeartbeatsRow{Once: true, LastSeenAt: now.Add(-5 * time.Second), LastSuccessAt: at(5 * time.Second)}, "running once"},
+		{"once finished", database.ListHeartbeatsRow{Once: true, IntervalSeconds: 1800, LastSeenAt: now.Add(-time.Hour), StoppedAt: at(time.Hour)}, "finished"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.hb.DbNow = now
+			if got := heartbeatState(tt.hb, defaultReadyIntervals); got != tt.want {
+				t.Errorf("heartbeatState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthReady(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		once        bool
+		lastSuccess time.Duration // ago, 0 for never
+		want        bool
+	}{
+		{"no scrape yet", time.Minute, false, 0, false},
+		{"recent success", time.Minute, false, time.Minute, true},
+		{"too long ago", time.Minute, false, 10 * time.Minute, false},
+		{"once --all", 0, true, 0, true},
+		{"once --all after a scrape", 0, true, time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h aggHealth
+			h.start(tt.interval, tt.once)
+			if tt.lastSuccess > 0 {
+				h.succeeded(now.Add(-tt.lastSuccess))
+			}
+			if got, reason := h.ready(now); got != tt.want {
+				t.Errorf("ready() = %t (%s), want %t", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunOnceExpiresHeartbeats(t *testing.T) {
+	agg, store, _, clock := newTestAggregator()
+	store.Heartbeats = map[string]database.AggregatorHeartbeat{
+		"old":    {Instance: "old", LastSeenAt: clock.now().Add(-heartbeatRetention - time.Hour)},
+		"recent": {Instance: "recent", LastSeenAt: clock.now().Add(-time.Hour)},
+	}
+	if err := agg.runOnce(context.Background(), 0, 1); err != nil {
+		t.Fatalf("runOnce() error: %v", err)
+	}
+	if _, ok := store.Heartbeats["old"]; ok {
+		t.Error("old heartbeat was not expired")
+	}
+	if _, ok := store.Heartbeats["recent"]; !ok {
+		t.Error("recent heartbeat was expired")
+	}
+	hb, ok := store.Heartbeats[agg.instance]
+	if !ok || !hb.Once || !hb.StoppedAt.Valid {
+		t.Errorf("own heartbeat = %+v, want a stopped one-shot run", hb)
+	}
+}
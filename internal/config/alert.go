@@ -0,0 +1,316 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"log/slog"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HandlerAlerts manages the user's alert rules and shows the alerts they
+// raised. The aggregator checks every new post of any feed against every
+// rule and records an alert for each match.
+//
+//	alerts [list] [--all] [limit]
+//	alerts add [--field any|title|description|author|url] [--regex] [--feed URL] <pattern>
+//	alerts rules
+//	alerts rm <id>
+//	alerts seen
+//
+// Rules match like mute rules. list shows the alerts not marked seen yet,
+// --all includes those that were.
+func HandlerAlerts(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return listAlerts(s, nil, user)
+	}
+	args := cmd.Args[1:]
+	switch cmd.Args[0] {
+	case "list":
+		return listAlerts(s, args, user)
+	case "add":
+		return addAlertRule(s, args, user)
+	case "rules":
+		return listAlertRules(s, user)
+	case "rm":
+		return removeAlertRule(s, args, user)
+	case "seen":
+		return markAlertsSeen(s, user)
+	default:
+		return fmt.Errorf("unknown alerts command: %s", cmd.Args[0])
+	}
+}
+
+func listAlerts(s *State, args []string, user database.User) error {
+	fs := newFlagSet("alerts list")
+	all := fs.Bool("all", false, "include alerts already marked seen")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	limit := 20
+	if len(args) == 1 {
+		if limit, err = parseLimit(args[0]); err != nil {
+			return err
+		}
+	}
+	alerts, err := s.Db.ListAlerts(s.Context(), database.ListAlertsParams{UserID: user.ID, IncludeSeen: *all, PageSize: int32(limit)})
+	if err != nil {
+		return fmt.Errorf("couldn't list alerts: %w", err)
+	}
+	if !s.Output.Text() {
+		records := make([]alertRecord, 0, len(alerts))
+		for _, a := range alerts {
+			records = append(records, alertRecord{
+				ID:        a.ID,
+				CreatedAt: a.CreatedAt,
+				User:      user.Name,
+				RuleID:    a.RuleID,
+				Pattern:   a.Pattern,
+				PostID:    a.PostID,
+				Title:     a.PostTitle,
+				URL:       a.PostUrl,
+				FeedName:  a.FeedName,
+				SeenAt:    nullTime(a.SeenAt),
+			})
+		}
+		return printRecords(s, records)
+	}
+	if len(alerts) == 0 {
+		if *all {
+			fmt.Println("No alerts")
+		} else {
+			fmt.Println("No new alerts")
+		}
+		return nil
+	}
+	for _, a := range alerts {
+		feedName := a.FeedName
+		if feedName == "" {
+			feedName = "a deleted feed"
+		}
+		status := ""
+		if !a.SeenAt.Valid {
+			status = " (new)"
+		}
+		fmt.Printf("%s %s from %s%s\n", a.CreatedAt.Format("Mon Jan 2 15:04"), a.Pattern, stripControl(feedName), status)
+		fmt.Printf("--- %s ---\n", stripControl(a.PostTitle))
+		fmt.Printf("Link: %s\n", stripControl(a.PostUrl))
+		fmt.Printf("Post ID: %s\n", a.PostID)
+		fmt.Println("-----------------------------------")
+	}
+	if !*all {
+		fmt.Println("Mark them seen with alerts seen")
+	}
+	return nil
+}
+
+func addAlertRule(s *State, args []string, user database.User) error {
+	fs := newFlagSet("alerts add")
+	field := fs.String("field", "any", "post field to match: "+strings.Join(muteFields, ", "))
+	regex := fs.Bool("regex", false, "match the pattern as a regular expression")
+	feedURL := fs.String("feed", "", "only alert on posts of this feed")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	pattern := strings.Join(args, " ")
+	if pattern == "" {
+		return errors.New("usage: alerts add [--field FIELD] [--regex] [--feed URL] <pattern>")
+	}
+	if !slices.Contains(muteFields, *field) {
+		return fmt.Errorf("unknown field %s, use one of %s", *field, strings.Join(muteFields, ", "))
+	}
+	if *regex {
+		// an invalid pattern would fail every scrape it is checked in
+		if err := s.Db.CheckRegex(s.Context(), pattern); err != nil {
+			return fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+	}
+	params := database.CreateAlertRuleParams{UserID: user.ID, Field: *field, Pattern: pattern, IsRegex: *regex}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeedByUrl(s.Context(), *feedURL)
+		if err != nil {
+			return fmt.Errorf("feed with URL %s does not exist", *feedURL)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	rule, err := s.Db.CreateAlertRule(s.Context(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't add alert rule: %w", err)
+	}
+	fmt.Printf("Alerting when %s (%s)\n", describeMute(rule.Field, rule.Pattern, rule.IsRegex, *feedURL), rule.ID)
+	return nil
+}
+
+func listAlertRules(s *State, user database.User) error {
+	rules, err := s.Db.ListAlertRules(s.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't list alert rules: %w", err)
+	}
+	if !s.Output.Text() {
+		records := make([]alertRuleRecord, 0, len(rules))
+		for _, r := range rules {
+			records = append(records, alertRuleRecord{ID: r.ID, Field: r.Field, Pattern: r.Pattern, Regex: r.IsRegex, FeedURL: r.FeedUrl.String, Raised: r.Raised, CreatedAt: r.CreatedAt})
+		}
+		return printRecords(s, records)
+	}
+	if len(rules) == 0 {
+		fmt.Println("No alert rules")
+		return nil
+	}
+	for _, r := range rules {
+		fmt.Printf("%s\n  %s, raised %d alerts\n", r.ID, describeMute(r.Field, r.Pattern, r.IsRegex, r.FeedUrl.String), r.Raised)
+	}
+	return nil
+}
+
+func removeAlertRule(s *State, args []string, user database.User) error {
+	if len(args) < 1 {
+		return errors.New("usage: alerts rm <id>")
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid alert rule ID %q, see alerts rules", args[0])
+	}
+	n, err := s.Db.DeleteAlertRule(s.Context(), database.DeleteAlertRuleParams{ID: id, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("couldn't remove alert rule: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("alert rule %s does not exist", id)
+	}
+	fmt.Printf("Alert rule %s and its alerts removed\n", id)
+	return nil
+}
+
+func markAlertsSeen(s *State, user database.User) error {
+	n, err := s.Db.MarkAlertsSeen(s.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't mark alerts seen: %w", err)
+	}
+	fmt.Printf("Marked %d alerts seen\n", n)
+	return nil
+}
+
+// AlertNotifier delivers the alerts raised while collecting a feed to a
+// channel other than the alerts table. Alerts are committed before they are
+// delivered, so alerts a notifier fails to deliver are still listed by the
+// alerts command.
+type AlertNotifier interface {
+	Notify(ctx context.Context, alerts []database.CreateAlertsRow) error
+}
+
+// logNotifier logs each alert. The aggregator uses it when it has no other
+// notifier.
+type logNotifier struct {
+	log *slog.Logger
+}
+
+func (n logNotifier) Notify(ctx context.Context, alerts []database.CreateAlertsRow) error {
+	for _, a := range alerts {
+		n.log.InfoContext(ctx, "alert raised",
+			"user", a.UserName,
+			"pattern", a.Pattern,
+			"feed", a.FeedName,
+			"title", a.PostTitle,
+			"url", a.PostUrl,
+		)
+	}
+	return nil
+}
+
+// defaultAlertTimeout is how long CommandNotifier lets its command run for
+// one alert when no Timeout is set.
+const defaultAlertTimeout = 30 * time.Second
+
+// CommandNotifier runs a shell command for each alert with the alert as a
+// JSON object on stdin, in the same format as alerts list --output json.
+// The alert's user, title and URL are also set in the GATOR_ALERT_USER,
+// GATOR_ALERT_TITLE and GATOR_ALERT_URL environment variables. A command
+// still running after Timeout is killed, so a hung command can't stall the
+// scrape delivering the alert.
+type CommandNotifier struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (n CommandNotifier) Notify(ctx context.Context, alerts []database.CreateAlertsRow) error {
+	var errs []error
+	for _, a := range alerts {
+		if err := n.notify(ctx, a); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n CommandNotifier) notify(ctx context.Context, a database.CreateAlertsRow) error {
+	data, err := json.Marshal(newAlertRecord(a))
+	if err != nil {
+		return err
+	}
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = defaultAlertTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", n.Command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"GATOR_ALERT_USER="+a.UserName,
+		"GATOR_ALERT_TITLE="+a.PostTitle,
+		"GATOR_ALERT_URL="+a.PostUrl,
+	)
+	// background processes the command started may hold its output open
+	// after it was killed
+	cmd.WaitDelay = time.Second
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %v", timeout)
+		}
+		return fmt.Errorf("alert command failed for %s: %w: %s", a.PostUrl, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func newAlertRecord(a database.CreateAlertsRow) alertRecord {
+	return alertRecord{
+		ID:        a.ID,
+		CreatedAt: a.CreatedAt,
+		User:      a.UserName,
+		RuleID:    a.RuleID,
+		Pattern:   a.Pattern,
+		PostID:    a.PostID,
+		Title:     a.PostTitle,
+		URL:       a.PostUrl,
+		FeedName:  a.FeedName,
+	}
+}
+
+// deliverAlerts hands alerts to the aggregator's notifier. Delivery errors
+// are logged and counted but don't fail the scrape, the alerts are already
+// stored.
+func (a *aggregator) deliverAlerts(ctx context.Context, alerts []database.CreateAlertsRow) {
+	if len(alerts) == 0 {
+		return
+	}
+	var notifier AlertNotifier = logNotifier{log: a.log}
+	if a.notifier != nil {
+		notifier = a.notifier
+	}
+	if err := notifier.Notify(ctx, alerts); err != nil {
+		a.metrics.alertErrors.Inc()
+		a.log.WarnContext(ctx, "failed to deliver alerts", "alerts", len(alerts), "error", err)
+	}
+}
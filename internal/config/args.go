@@ -0,0 +1,56 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// newFlagSet returns a flag set for a command's arguments. Errors are
+// returned to the caller rather than printed and exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseArgs parses args with fs, allowing flags to appear before, between
+// or after positional arguments. It returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseLimit parses the number of results a listing command shows, which
+// must be positive.
+func parseLimit(s string) (int, error) {
+	limit, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid limit: %w", err)
+	}
+	if err := checkLimit(limit); err != nil {
+		return 0, err
+	}
+	return limit, nil
+}
+
+func checkLimit(limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("limit must be positive, got %d", limit)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotLoggedIn is returned by CurrentUser when there is no valid session.
+var ErrNotLoggedIn = errors.New("not logged in, run gator login <username>")
+
+// ErrNotAdmin is returned when a command needs an admin.
+var ErrNotAdmin = errors.New("permission denied, only admins can do that")
+
+// RequireAdmin fails with ErrNotAdmin unless user is an admin.
+func RequireAdmin(user database.User) error {
+	if !user.IsAdmin {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+// requireSelfOrAdmin lets users manage their own account and admins manage
+// anyone's.
+func requireSelfOrAdmin(user database.User, targetID uuid.UUID) error {
+	if user.ID != targetID {
+		return RequireAdmin(user)
+	}
+	return nil
+}
+
+// CurrentUser returns the user owning the session token in the config. It
+// fails if the session has expired or been revoked.
+func (s *State) CurrentUser() (database.User, error) {
+	if s.Profile.SessionToken == "" {
+		return database.User{}, ErrNotLoggedIn
+	}
+	user, err := s.Db.GetSessionUser(s.Context(), hashToken(s.Profile.SessionToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("session expired or revoked: %w", ErrNotLoggedIn)
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("failed to get current user: %w", err)
+	}
+	return user, nil
+}
+
+// startSession creates a session for user and stores its token in the
+// config, revoking the session it replaces.
+func startSession(s *State, user database.User) error {
+	token, err := newSessionToken()
+	if err != nil {
+		return fmt.Errorf("failed to create session token: %w", err)
+	}
+	_, err = s.Db.CreateSession(s.Context(), database.CreateSessionParams{
+		UserID:     user.ID,
+		TokenHash:  hashToken(token),
+		TtlSeconds: sessionTTL.Seconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	if old := s.Profile.SessionToken; old != "" {
+		if err := s.Db.RevokeSession(s.Context(), hashToken(old)); err != nil {
+			return fmt.Errorf("failed to revoke previous session: %w", err)
+		}
+	}
+	s.Profile.SessionToken = token
+	return nil
+}
+
+func setPassword(s *State, user database.User, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	err = s.Db.SetUserPassword(s.Context(), database.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: sql.NullString{String: hash, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+	return nil
+}
+
+// HandlerLogout revokes the current session, or with --all every session
+// of the current user on any machine.
+func HandlerLogout(s *State, cmd Command) error {
+	fs := newFlagSet("logout")
+	all := fs.Bool("all", false, "revoke every session of the current user")
+	if _, err := parseArgs(fs, cmd.Args); err != nil {
+		return err
+	}
+	if s.Profile.SessionToken == "" {
+		fmt.Println("Not logged in")
+		return nil
+	}
+
+	if *all {
+		user, err := s.CurrentUser()
+		if err != nil {
+			return err
+		}
+		n, err := s.Db.RevokeUserSessions(s.Context(), user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		fmt.Printf("Revoked %d sessions for %s\n", n, user.Name)
+	} else if err := s.Db.RevokeSession(s.Context(), hashToken(s.Profile.SessionToken)); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	} else {
+		fmt.Println("Logged out")
+	}
+	s.Profile.SessionToken = ""
+	return nil
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"gator/internal/database"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HandlerBrowse lists posts in the feeds the user follows, newest first.
+//
+//	browse [--unread] [--feed URL] [--folder NAME] [--tag TAG] [--since DATE] [--until DATE] [--oldest-first] [--after CURSOR] [--show-muted] [limit]
+//
+// Pages are fetched by keyset rather than offset: each page ends with a
+// cursor, and passing it to --after with the same flags shows the next page.
+// A cursor is rejected when the flags differ from those it was made with.
+// Posts matching the user's mute rules are hidden unless --show-muted is set.
+func HandlerBrowse(s *State, cmd Command, user database.User) error {
+	fs := newFlagSet("browse")
+	unread := fs.Bool("unread", false, "only show posts you haven't read")
+	feedURL := fs.String("feed", "", "only show posts of this feed")
+	folder := fs.String("folder", "", "only show posts of the feeds in this folder")
+	tag := fs.String("tag", "", "only show posts of the feeds with this tag")
+	since := fs.String("since", "", "only posts published on or after this date")
+	until := fs.String("until", "", "only posts published before the end of this date")
+	oldestFirst := fs.Bool("oldest-first", false, "show the oldest posts first")
+	after := fs.String("after", "", "continue after this cursor")
+	showMuted := fs.Bool("show-muted", false, "include posts hidden by mute rules")
+	args, err := parseArgs(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	limit := 2
+	if len(args) == 1 {
+		if limit, err = parseLimit(args[0]); err != nil {
+			return err
+		}
+	}
+
+	params := database.BrowsePostsParams{
+		UserID:     user.ID,
+		UnreadOnly: *unread,
+		ShowMuted:  *showMuted,
+		PageSize:   int32(limit),
+	}
+	if *feedURL != "" {
+		params.FeedUrl = sql.NullString{String: *feedURL, Valid: true}
+	}
+	if *folder != "" {
+		if _, err := lookupFolder(s, user, *folder); err != nil {
+			return err
+		}
+		params.Folder = sql.NullString{String: *folder, Valid: true}
+	}
+	if *tag != "" {
+		if err := lookupTag(s, user, *tag); err != nil {
+			return err
+		}
+		params.Tag = sql.NullString{String: *tag, Valid: true}
+	}
+	if params.Since, params.Until, err = parseDateRange(*since, *until); err != nil {
+		return err
+	}
+	key := browseKey(params, *oldestFirst)
+	if *after != "" {
+		postedAt, id, err := decodeCursor(*after, key)
+		if err != nil {
+			return err
+		}
+		params.AfterPostedAt = sql.NullTime{Time: postedAt, Valid: true}
+		params.AfterID = id
+	}
+
+	var posts []database.BrowsePostsRow
+	if *oldestFirst {
+		rows, err := s.Db.BrowsePostsOldestFirst(s.Context(), database.BrowsePostsOldestFirstParams(params))
+		if err != nil {
+			return fmt.Errorf("couldn't get posts for user: %w", err)
+		}
+		for _, row := range rows {
+			posts = append(posts, database.BrowsePostsRow(row))
+		}
+	} else {
+		posts, err = s.Db.BrowsePosts(s.Context(), params)
+		if err != nil {
+			return fmt.Errorf("couldn't get posts for user: %w", err)
+		}
+	}
+
+	if !s.Output.Text() {
+		records := make([]postRecord, 0, len(posts))
+		for _, post := range posts {
+			record := browseSummary(post).record()
+			record.Cursor = encodeCursor(post.PostedAt, post.ID, key)
+			records = append(records, record)
+		}
+		return printRecords(s, records)
+	}
+	fmt.Printf("Found %d posts for user %s:\n", len(posts), user.Name)
+	for _, post := range posts {
+		printPost(browseSummary(post))
+	}
+	if len(posts) > 0 && len(posts) == limit {
+		last := posts[len(posts)-1]
+		fmt.Printf("More posts: browse %s\n", nextPageArgs(fs, encodeCursor(last.PostedAt, last.ID, key), args))
+	}
+	if !*showMuted {
+		muted, err := s.Db.CountMutedPosts(s.Context(), database.CountMutedPostsParams{
+			UserID:     params.UserID,
+			UnreadOnly: params.UnreadOnly,
+			FeedUrl:    params.FeedUrl,
+			Folder:     params.Folder,
+			Tag:        params.Tag,
+			Since:      params.Since,
+			Until:      params.Until,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't count muted posts: %w", err)
+		}
+		if muted > 0 {
+			fmt.Printf("%d posts hidden by mute rules, pass --show-muted to see them\n", muted)
+		}
+	}
+
+	return nil
+}
+
+func browseSummary(post database.BrowsePostsRow) postSummary {
+	return postSummary{
+		ID:          post.ID,
+		Title:       post.Title,
+		Url:         post.Url,
+		Description: post.Description,
+		PublishedAt: post.PublishedAt,
+		FeedName:    post.FeedName,
+		IsRead:      post.IsRead,
+		IsStarred:   post.IsStarred,
+		IsMuted:     post.IsMuted,
+	}
+}
+
+// browseKey identifies the order and filters of a listing, so a cursor is
+// only used to continue the listing it came from.
+func browseKey(params database.BrowsePostsParams, oldestFirst bool) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%t %t %t %q %q %q %s %s", oldestFirst, params.UnreadOnly, params.ShowMuted,
+		params.FeedUrl.String, params.Folder.String, params.Tag.String, formatCursorTime(params.Since), formatCursorTime(params.Until))
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
+
+func formatCursorTime(t sql.NullTime) string {
+	if !t.Valid {
+		return "-"
+	}
+	return t.Time.UTC().Format(time.RFC3339Nano)
+}
+
+// nextPageArgs returns the arguments that show the page after cursor: the
+// flags set on this invocation with --after replaced, then positional.
+func nextPageArgs(fs *flag.FlagSet, cursor string, positional []string) string {
+	var args []string
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "after" {
+			return
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			if f.Value.String() == "true" {
+				args = append(args, "--"+f.Name)
+			} else {
+				args = append(args, "--"+f.Name+"="+f.Value.String())
+			}
+			return
+		}
+		args = append(args, "--"+f.Name, shellQuote(f.Value.String()))
+	})
+	args = append(args, "--after", cursor)
+	for _, arg := range positional {
+		args = append(args, shellQuote(arg))
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for pasting into a shell when it needs it.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || r == '/' || r == ':' || r == '=' || r == '+' ||
+			'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// encodeCursor turns the sort key of the last post on a page and the key
+// of the listing into an opaque string for --after.
+func encodeCursor(postedAt time.Time, id uuid.UUID, key string) string {
+	raw := postedAt.UTC().Format(time.RFC3339Nano) + " " + id.String() + " " + key
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor, key string) (time.Time, uuid.UUID, error) {
+	invalid := errors.New("invalid cursor, use the one printed by the previous page")
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) != 3 {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	postedAt, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	id, err := uuid.Parse(fields[1])
+	if err != nil {
+		return time.Time{}, uuid.Nil, invalid
+	}
+	if fields[2] != key {
+		return time.Time{}, uuid.Nil, errors.New("the cursor belongs to a listing with other flags, pass the same flags as for the previous page")
+	}
+	return postedAt.UTC(), id, nil
+}
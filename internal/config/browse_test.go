@@ -0,0 +1,66 @@
+package config
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"gator/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	postedAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	id := uuid.New()
+	key := browseKey(database.BrowsePostsParams{UnreadOnly: true}, false)
+	gotAt, gotID, err := decodeCursor(encodeCursor(postedAt, id, key), key)
+	if err != nil {
+		t.Fatalf("decodeCursor() error: %v", err)
+	}
+	if !gotAt.Equal(postedAt) || gotID != id {
+		t.Errorf("decodeCursor() = %v, %v, want %v, %v", gotAt, gotID, postedAt, id)
+	}
+}
+
+func TestCursorRejectsOtherFlags(t *testing.T) {
+	base := database.BrowsePostsParams{}
+	since := sql.NullTime{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	tests := []struct {
+		name        string
+		params      database.BrowsePostsParams
+		oldestFirst bool
+	}{
+		{"oldest first", base, true},
+		{"unread", database.BrowsePostsParams{UnreadOnly: true}, false},
+		{"show muted", database.BrowsePostsParams{ShowMuted: true}, false},
+		{"feed", database.BrowsePostsParams{FeedUrl: sql.NullString{String: "https://example.com/feed", Valid: true}}, false},
+		{"folder", database.BrowsePostsParams{Folder: sql.NullString{String: "news", Valid: true}}, false},
+		{"tag", database.BrowsePostsParams{Tag: sql.NullString{String: "news", Valid: true}}, false},
+		{"since", database.BrowsePostsParams{Since: since}, false},
+	}
+	cursor := encodeCursor(time.Now(), uuid.New(), browseKey(base, false))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCursor(cursor, browseKey(tt.params, tt.oldestFirst)); err == nil {
+				t.Error("decodeCursor() accepted a cursor from a listing with other flags")
+			}
+		})
+	}
+}
+
+func TestNextPageArgs(t *testing.T) {
+	fs := newFlagSet("browse")
+	fs.Bool("unread", false, "")
+	fs.Bool("oldest-first", false, "")
+	fs.String("folder", "", "")
+	fs.String("after", "", "")
+	args, err := parseArgs(fs, []string{"--unread", "--folder", "tech news", "--oldest-first=false", "--after", "old", "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--folder 'tech news' --oldest-first=false --unread --after next 5"
+	if got := nextPageArgs(fs, "next", args); got != want {
+		t.Errorf("nextPageArgs() = %q, want %q", got, want)
+	}
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"gator/internal/database"
@@ -11,12 +13,36 @@ import (
 const configFileName = ".gatorconfig.json"
 
 type Config struct { //DB connec config w JSON attachment
-	DBURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name,omitempty"`
+	CurrentProfile string              `json:"current_profile,omitempty"`
+	Profiles       map[string]*Profile `json:"profiles,omitempty"`
+	LogLevel       string              `json:"log_level,omitempty"`
+	LogFormat      string              `json:"log_format,omitempty"`
+
+	// DBURL and SessionToken are read from config files written before
+	// profiles existed and moved into the default profile.
+	DBURL        string `json:"db_url,omitempty"`
+	SessionToken string `json:"session_token,omitempty"`
 }
 type State struct {
 	ConfigPtr *Config
+	Profile   *Profile
 	Db        *database.Queries
+	Conn      *sql.DB
+	Fetcher   Fetcher
+	// Notifier delivers the alerts agg raises, agg logs them when it is
+	// nil and --alert-command replaces it.
+	Notifier AlertNotifier
+	Output   OutputFormat
+	Ctx      context.Context
+}
+
+// Context returns the context commands should run under, it is cancelled
+// when the process is asked to shut down.
+func (s *State) Context() context.Context {
+	if s.Ctx == nil {
+		return context.Background()
+	}
+	return s.Ctx
 }
 
 type Command struct {
@@ -58,6 +84,7 @@ func Read() (Config, error) {
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return Config{}, err
 	}
+	cfg.migrateLegacyProfile()
 
 	return cfg, nil
 }
@@ -83,10 +110,10 @@ func Write(cfg Config) error {
 		return err
 	}
 
-	return os.WriteFile(filepath, data, 0644)
+	// the file holds a session token, keep it private
+	if err := os.WriteFile(filepath, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(filepath, 0600)
 
 }
-func (cfg *Config) SetUser(user string) error {
-	cfg.CurrentUserName = user
-	return Write(*cfg)
-}
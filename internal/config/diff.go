@@ -0,0 +1,84 @@
+package config
+
+import "strings"
+
+// maxDiffCells caps the size of the table diffLines builds. Past it the
+// changed lines are shown as all removed and then all added.
+const maxDiffCells = 1 << 20
+
+// diffLines returns a line diff turning a into b. Removed lines are prefixed
+// with "- ", added lines with "+ " and unchanged lines with "  ".
+func diffLines(a, b string) []string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// lines shared at the start and end are unchanged, only the middle
+	// needs diffing
+	var prefix, suffix []string
+	for len(x) > 0 && len(y) > 0 && x[0] == y[0] {
+		prefix = append(prefix, "  "+x[0])
+		x, y = x[1:], y[1:]
+	}
+	for len(x) > 0 && len(y) > 0 && x[len(x)-1] == y[len(y)-1] {
+		suffix = append(suffix, "  "+x[len(x)-1])
+		x, y = x[:len(x)-1], y[:len(y)-1]
+	}
+
+	out := prefix
+	if (len(x)+1)*(len(y)+1) > maxDiffCells {
+		for _, line := range x {
+			out = append(out, "- "+line)
+		}
+		for _, line := range y {
+			out = append(out, "+ "+line)
+		}
+	} else {
+		out = append(out, diffMiddle(x, y)...)
+	}
+	for i := len(suffix) - 1; i >= 0; i-- {
+		out = append(out, suffix[i])
+	}
+	return out
+}
+
+// diffMiddle diffs x and y through their longest common subsequence.
+func diffMiddle(x, y []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, "  "+x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+x[i])
+			i++
+		default:
+			out = append(out, "+ "+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, "- "+x[i])
+	}
+	for ; j < len(y); j++ {
+		out = append(out, "+ "+y[j])
+	}
+	return out
+}
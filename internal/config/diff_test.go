@@ -0,0 +1,44 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"unchanged", "a\nb", "a\nb", []string{"  a", "  b"}},
+		{"line changed", "a\nb\nc", "a\nx\nc", []string{"  a", "- b", "+ x", "  c"}},
+		{"line added", "a\nc", "a\nb\nc", []string{"  a", "+ b", "  c"}},
+		{"line removed", "a\nb\nc", "a\nc", []string{"  a", "- b", "  c"}},
+		{"from empty", "", "a", []string{"- ", "+ a"}},
+		{"moved line", "a\nb\nc", "b\nc\na", []string{"- a", "  b", "  c", "+ a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.a, tt.b); !slices.Equal(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesLargeInput(t *testing.T) {
+	var a, b []string
+	for i := range 2000 {
+		a = append(a, "old "+strings.Repeat("x", i%7))
+		b = append(b, "new "+strings.Repeat("x", i%7))
+	}
+	got := diffLines("same\n"+strings.Join(a, "\n")+"\nend", "same\n"+strings.Join(b, "\n")+"\nend")
+	if len(got) != 2+len(a)+len(b) {
+		t.Fatalf("diffLines() returned %d lines, want %d", len(got), 2+len(a)+len(b))
+	}
+	if got[0] != "  same" || got[1] != "- "+a[0] || got[1+len(a)] != "+ "+b[0] || got[len(got)-1] != "  end" {
+		t.Errorf("diffLines() = %q..., want the common lines kept and the rest removed then added", got[:3])
+	}
+}
@@ -6,18 +6,38 @@ import (
 	"fmt"
 	"html"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
+// Fetcher retrieves and parses the RSS feed at a URL. The aggregator only
+// talks to feeds through this interface so it can be swapped for a fake.
+type Fetcher interface {
+	Fetch(ctx context.Context, feedURL string) (*RSSFeed, error)
+}
+
+// HTTPFetcher is the default Fetcher, it downloads feeds over HTTP.
+type HTTPFetcher struct {
+	Client    *http.Client
+	UserAgent string
+}
+
+func (f HTTPFetcher) Fetch(ctx context.Context, feedURL string) (*RSSFeed, error) {
+	client := f.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
-	req.Header.Set("User-Agent", "gator")
+	userAgent := f.UserAgent
+	if userAgent == "" {
+		userAgent = "gator"
+	}
+	req.Header.Set("User-Agent", userAgent)
 	resp, err := client.Do(req)
 
 	if err != nil {
@@ -28,13 +48,21 @@ func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	var feed RSSFeed
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("error reading response body: %w", err)
 	}
-	fmt.Println("XML sample:", string(data[:min(200, len(data))]))
-	err = xml.Unmarshal(data, &feed)
+	slog.DebugContext(ctx, "fetched feed body", "url", feedURL, "bytes", len(data), "sample", string(data[:min(200, len(data))]))
+	return parseFeed(data)
+}
+
+func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+	return HTTPFetcher{}.Fetch(ctx, feedURL)
+}
+
+func parseFeed(data []byte) (*RSSFeed, error) {
+	var feed RSSFeed
+	err := xml.Unmarshal(data, &feed)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling XML: %w", err)
 	}
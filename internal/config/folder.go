@@ -0,0 +1,164 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// HandlerFolder organizes the feeds the user follows into folders. Folders
+// belong to one user, removing one leaves its feeds unfiled.
+//
+//	folder add <name>
+//	folder rm <name>
+//	folder mv <feed_url> [name]
+//	folder rename <name> <new_name>
+//
+// mv without a folder name takes the feed out of its folder.
+func HandlerFolder(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("usage: folder add <name> | rm <name> | mv <feed_url> [name] | rename <name> <new_name>")
+	}
+	args := cmd.Args[1:]
+	switch cmd.Args[0] {
+	case "add":
+		return addFolder(s, args, user)
+	case "rm":
+		return removeFolder(s, args, user)
+	case "mv":
+		return moveToFolder(s, args, user)
+	case "rename":
+		return renameFolder(s, args, user)
+	default:
+		return fmt.Errorf("unknown folder command: %s", cmd.Args[0])
+	}
+}
+
+// lookupFolder fetches one of the user's folders by name.
+func lookupFolder(s *State, user database.User, name string) (database.Folder, error) {
+	folder, err := s.Db.GetFolder(s.Context(), database.GetFolderParams{UserID: user.ID, Name: name})
+	if errors.Is(err, sql.ErrNoRows) {
+		return folder, fmt.Errorf("folder %s does not exist", name)
+	}
+	if err != nil {
+		return folder, fmt.Errorf("couldn't get folder %s: %w", name, err)
+	}
+	return folder, nil
+}
+
+func addFolder(s *State, args []string, user database.User) error {
+	if len(args) < 1 || args[0] == "" {
+		return errors.New("usage: folder add <name>")
+	}
+	if _, err := lookupFolder(s, user, args[0]); err == nil {
+		return fmt.Errorf("folder %s already exists", args[0])
+	}
+	folder, err := s.Db.CreateFolder(s.Context(), database.CreateFolderParams{UserID: user.ID, Name: args[0]})
+	if err != nil {
+		return fmt.Errorf("couldn't create folder: %w", err)
+	}
+	fmt.Printf("Folder %s created\n", folder.Name)
+	return nil
+}
+
+func removeFolder(s *State, args []string, user database.User) error {
+	if len(args) < 1 {
+		return errors.New("usage: folder rm <name>")
+	}
+	n, err := s.Db.DeleteFolder(s.Context(), database.DeleteFolderParams{UserID: user.ID, Name: args[0]})
+	if err != nil {
+		return fmt.Errorf("couldn't remove folder %s: %w", args[0], err)
+	}
+	if n == 0 {
+		return fmt.Errorf("folder %s does not exist", args[0])
+	}
+	fmt.Printf("Folder %s removed, its feeds are now unfiled\n", args[0])
+	return nil
+}
+
+func moveToFolder(s *State, args []string, user database.User) error {
+	if len(args) < 1 {
+		return errors.New("usage: folder mv <feed_url> [name]")
+	}
+	params := database.SetFollowFolderParams{UserID: user.ID, FeedUrl: args[0]}
+	if len(args) > 1 {
+		folder, err := lookupFolder(s, user, args[1])
+		if err != nil {
+			return fmt.Errorf("%w, create it with folder add %s", err, args[1])
+		}
+		params.FolderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+	n, err := s.Db.SetFollowFolder(s.Context(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't move feed: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("you don't follow a feed with URL %s", args[0])
+	}
+	if len(args) > 1 {
+		fmt.Printf("Feed %s moved to %s\n", args[0], args[1])
+	} else {
+		fmt.Printf("Feed %s is now unfiled\n", args[0])
+	}
+	return nil
+}
+
+func renameFolder(s *State, args []string, user database.User) error {
+	if len(args) < 2 || args[1] == "" {
+		return errors.New("usage: folder rename <name> <new_name>")
+	}
+	if _, err := lookupFolder(s, user, args[1]); err == nil {
+		return fmt.Errorf("folder %s already exists", args[1])
+	}
+	n, err := s.Db.RenameFolder(s.Context(), database.RenameFolderParams{NewName: args[1], UserID: user.ID, Name: args[0]})
+	if err != nil {
+		return fmt.Errorf("couldn't rename folder: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("folder %s does not exist", args[0])
+	}
+	fmt.Printf("Folder %s renamed to %s\n", args[0], args[1])
+	return nil
+}
+
+// printFollowTree prints the user's folders with the feeds in each, then
+// the feeds not in any folder.
+func printFollowTree(s *State, user database.User, follows []database.GetFeedFollowsForUserRow) error {
+	folders, err := s.Db.ListFolders(s.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't list folders: %w", err)
+	}
+	if len(follows) == 0 && len(folders) == 0 {
+		fmt.Println("No feeds followed")
+		return nil
+	}
+	byFolder := make(map[uuid.UUID][]database.GetFeedFollowsForUserRow)
+	var unfiled []database.GetFeedFollowsForUserRow
+	for _, ff := range follows {
+		if ff.FolderID.Valid {
+			byFolder[ff.FolderID.UUID] = append(byFolder[ff.FolderID.UUID], ff)
+		} else {
+			unfiled = append(unfiled, ff)
+		}
+	}
+	printGroup := func(name string, group []database.GetFeedFollowsForUserRow) {
+		unread := int64(0)
+		for _, ff := range group {
+			unread += ff.Unread
+		}
+		fmt.Printf("%s (%d unread)\n", name, unread)
+		for _, ff := range group {
+			fmt.Printf("  %s (%d unread)%s\n", ff.FeedName, ff.Unread, formatTags(ff.Tags))
+		}
+	}
+	for _, folder := range folders {
+		printGroup(folder.Name+"/", byFolder[folder.ID])
+	}
+	if len(unfiled) > 0 {
+		printGroup("Unfiled", unfiled)
+	}
+	return nil
+}
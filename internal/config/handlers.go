@@ -1,14 +1,10 @@
 package config
 
 import (
-	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"gator/internal/database"
-	"log"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -20,15 +16,28 @@ func HandlerLogin(s *State, cmd Command) error {
 		return errors.New("login username required")
 	}
 
-	_, err := s.Db.GetUser(context.Background(), cmd.Args[0])
+	user, err := s.Db.GetUser(s.Context(), cmd.Args[0])
 	if err != nil {
-		fmt.Printf("User with name %s does not exist\n", cmd.Args[0])
+		return fmt.Errorf("user with name %s does not exist", cmd.Args[0])
+	}
 
-		os.Exit(1)
+	if !user.PasswordHash.Valid {
+		// accounts created before passwords were required can't be
+		// claimed by whoever logs in first
+		return fmt.Errorf("user %s has no password, ask an admin to set one with user passwd %s", user.Name, user.Name)
+	}
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+	if !checkPassword(user.PasswordHash.String, password) {
+		return errors.New("incorrect username or password")
 	}
 
-	s.ConfigPtr.CurrentUserName = cmd.Args[0]
-	fmt.Printf("Current user set to %s\n", s.ConfigPtr.CurrentUserName)
+	if err := startSession(s, user); err != nil {
+		return err
+	}
+	fmt.Printf("Current user set to %s\n", user.Name)
 
 	return nil
 }
@@ -39,45 +48,65 @@ func HandlerRegister(s *State, cmd Command) error {
 		return errors.New("username required for registration")
 	}
 
-	_, err := s.Db.GetUser(context.Background(), cmd.Args[0])
+	_, err := s.Db.GetUser(s.Context(), cmd.Args[0])
 	if err == nil {
-		fmt.Printf("User with name '%s' already exists\n", cmd.Args[0])
-		os.Exit(1)
+		return fmt.Errorf("user with name '%s' already exists", cmd.Args[0])
+	}
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
 	}
 	user := database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      cmd.Args[0],
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Name:         cmd.Args[0],
+		PasswordHash: sql.NullString{String: hash, Valid: true},
 	}
 
-	newUser, err := s.Db.CreateUser(context.Background(), user)
+	newUser, err := s.Db.CreateUser(s.Context(), user)
 	if err != nil {
-		println("User creation failed, user already exists")
-		os.Exit(1)
+		return fmt.Errorf("user creation failed: %w", err)
+	}
+	if err := startSession(s, newUser); err != nil {
+		return err
 	}
-	s.ConfigPtr.CurrentUserName = newUser.Name
 	fmt.Printf("User creation successful, Name: %s ID: %v Time: %v\n", user.Name, user.ID, user.CreatedAt)
+	if newUser.IsAdmin {
+		fmt.Println("You are the first user and have been made an admin")
+	}
 	return nil
 }
 
-func HandlerReset(s *State, cmd Command) error {
-
-	s.Db.ResetTable(context.Background())
-
-	return nil
-}
 func HandlerList(s *State, cmd Command) error {
 
-	users, err := s.Db.ListUsers(context.Background())
+	users, err := s.Db.ListUsers(s.Context())
 	if err != nil {
-		fmt.Println("Failed to find users for listing")
-		os.Exit(1)
+		return fmt.Errorf("failed to find users for listing: %w", err)
+	}
+	// not being logged in is fine here, nobody is marked current
+	current, _ := s.CurrentUser()
+	if !s.Output.Text() {
+		records := make([]userRecord, 0, len(users))
+		for _, user := range users {
+			records = append(records, userRecord{ID: user.ID, Name: user.Name, CreatedAt: user.CreatedAt, Admin: user.IsAdmin, Current: user.ID == current.ID})
+		}
+		return printRecords(s, records)
 	}
-	currentUser := s.ConfigPtr.CurrentUserName
 	for _, user := range users {
-		if user.Name == currentUser {
-			fmt.Printf("* %s (current)\n", user.Name)
+		var tags []string
+		if user.IsAdmin {
+			tags = append(tags, "admin")
+		}
+		if user.ID == current.ID {
+			tags = append(tags, "current")
+		}
+		if len(tags) > 0 {
+			fmt.Printf("* %s (%s)\n", user.Name, strings.Join(tags, ", "))
 		} else {
 			fmt.Printf("* %s\n", user.Name)
 		}
@@ -85,22 +114,6 @@ func HandlerList(s *State, cmd Command) error {
 	return nil
 }
 
-func Agg(s *State, cmd Command) error {
-	//neds protection logic against incorrect user input
-	time_between_reqs := cmd.Args[0]
-	parsedDuration, err := time.ParseDuration(time_between_reqs)
-	if err != nil {
-		return err
-	}
-	fmt.Println("Collecting feeds every \n", parsedDuration)
-
-	ticker := time.NewTicker(parsedDuration)
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
-	}
-
-}
-
 func AddFeed(s *State, cmd Command, user database.User) error {
 	if len(cmd.Args) < 2 {
 		return fmt.Errorf("requires feed name and URL")
@@ -108,7 +121,7 @@ func AddFeed(s *State, cmd Command, user database.User) error {
 	name := cmd.Args[0]
 	url := cmd.Args[1]
 
-	feed, err := s.Db.CreateFeed(context.Background(), database.CreateFeedParams{Name: name, Url: url, UserID: uuid.NullUUID{UUID: user.ID, Valid: true}})
+	feed, err := s.Db.CreateFeed(s.Context(), database.CreateFeedParams{Name: name, Url: url, UserID: uuid.NullUUID{UUID: user.ID, Valid: true}})
 
 	if err != nil {
 		return fmt.Errorf("failed to create feed: %v", err)
@@ -119,7 +132,7 @@ func AddFeed(s *State, cmd Command, user database.User) error {
 		UserID: user.ID,
 		FeedID: feed.ID,
 	}
-	feedFollows, err := s.Db.CreateFeedFollow(context.Background(), followParams)
+	feedFollows, err := s.Db.CreateFeedFollow(s.Context(), followParams)
 	if err != nil {
 		return fmt.Errorf("failed to create feed follow record: %v", err)
 	}
@@ -132,10 +145,17 @@ func AddFeed(s *State, cmd Command, user database.User) error {
 }
 
 func HandlerFeedsDisplay(s *State, cmd Command) error {
-	feeds, err := s.Db.ListFeedsWithUsers(context.Background())
+	feeds, err := s.Db.ListFeedsWithUsers(s.Context())
 	if err != nil {
 		return fmt.Errorf("failed to get feeds %v", err)
 	}
+	if !s.Output.Text() {
+		records := make([]feedRecord, 0, len(feeds))
+		for _, feed := range feeds {
+			records = append(records, feedRecord{Name: feed.FeedName, URL: feed.FeedsUrl, AddedBy: feed.UserName})
+		}
+		return printRecords(s, records)
+	}
 
 	for _, feed := range feeds {
 		fmt.Printf("Feed Name: %v\n", feed.FeedName)
@@ -153,13 +173,13 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 
 	url := cmd.Args[0]
 
-	feed, err := s.Db.GetFeedByUrl(context.Background(), url)
+	feed, err := s.Db.GetFeedByUrl(s.Context(), url)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve feed, error: %v", err)
 	}
 	params := database.CreateFeedFollowParams{UserID: user.ID, FeedID: feed.ID}
 
-	feedFollows, err := s.Db.CreateFeedFollow(context.Background(), params)
+	feedFollows, err := s.Db.CreateFeedFollow(s.Context(), params)
 	if err != nil {
 		return fmt.Errorf("failed to create feed follows entry, error: %v", err)
 	}
@@ -170,19 +190,41 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
+// HandlerFollowing lists the feeds the user follows, grouped by folder
+// with --tree.
+//
+//	following [--tree]
 func HandlerFollowing(s *State, cmd Command, user database.User) error {
+	fs := newFlagSet("following")
+	tree := fs.Bool("tree", false, "group feeds by folder")
+	if _, err := parseArgs(fs, cmd.Args); err != nil {
+		return err
+	}
+	if *tree && !s.Output.Text() {
+		return errors.New("--tree only prints text, the records of the other output formats have each feed's folder")
+	}
 
-	feedFollows, err := s.Db.GetFeedFollowsForUser(context.Background(), user.ID)
+	feedFollows, err := s.Db.GetFeedFollowsForUser(s.Context(), user.ID)
 	if err != nil {
 		return fmt.Errorf("failed to grab feedfollows for currentf user, error: %v", err)
 	}
+	if !s.Output.Text() {
+		records := make([]followRecord, 0, len(feedFollows))
+		for _, ff := range feedFollows {
+			records = append(records, followRecord{FeedID: ff.FeedID, FeedName: ff.FeedName, FeedURL: ff.FeedUrl, Folder: ff.FolderName.String, Tags: ff.Tags, Unread: ff.Unread, FollowedAt: ff.CreatedAt})
+		}
+		return printRecords(s, records)
+	}
+	if *tree {
+		return printFollowTree(s, user, feedFollows)
+	}
 	if len(feedFollows) == 0 {
 		fmt.Println("Follow command success, no feeds followed")
 		return nil
 	}
 	fmt.Println("Followed feeds: ")
 	for i, ff := range feedFollows {
-		fmt.Printf("%d. %s\n", i+1, ff.FeedName)
+		fmt.Printf("%d. %s (%d unread)%s\n", i+1, ff.FeedName, ff.Unread, formatTags(ff.Tags))
 	}
 	return nil
 }
@@ -194,7 +236,7 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 	targeturl := cmd.Args[0]
 
 	params := database.DeleteFeedFollowParams{UserID: user.ID, Url: targeturl}
-	err := s.Db.DeleteFeedFollow(context.Background(), params)
+	err := s.Db.DeleteFeedFollow(s.Context(), params)
 	if err != nil {
 		return fmt.Errorf("failed to grab delete for , error: %v", err)
 	}
@@ -202,90 +244,120 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
-func HandlerBrowse(s *State, cmd Command, user database.User) error {
-	limit := 2
-	if len(cmd.Args) == 1 {
-		if specifiedLimit, err := strconv.Atoi(cmd.Args[0]); err == nil {
-			limit = specifiedLimit
-		} else {
-			return fmt.Errorf("invalid limit: %w", err)
-		}
+// HandlerRenameFollow sets the title the user sees for a feed they follow
+// instead of the feed's name. Without a title the feed's name is used again.
+//
+//	rename-follow <url> [title]
+func HandlerRenameFollow(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: rename-follow <url> [title]")
 	}
-
-	posts, err := s.Db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	})
+	url := cmd.Args[0]
+	title := strings.TrimSpace(strings.Join(cmd.Args[1:], " "))
+	params := database.RenameFollowParams{UserID: user.ID, FeedUrl: url}
+	if title != "" {
+		params.Title = sql.NullString{String: title, Valid: true}
+	}
+	n, err := s.Db.RenameFollow(s.Context(), params)
 	if err != nil {
-		return fmt.Errorf("couldn't get posts for user: %w", err)
+		return fmt.Errorf("couldn't rename follow: %w", err)
 	}
-
-	fmt.Printf("Found %d posts for user %s:\n", len(posts), user.Name)
-	for _, post := range posts {
-		fmt.Printf("%s from %s\n", post.PublishedAt.Time.Format("Mon Jan 2"), post.FeedName)
-		fmt.Printf("--- %s ---\n", post.Title)
-		fmt.Printf("    %v\n", post.Description.String)
-		fmt.Printf("Link: %s\n", post.Url)
-		fmt.Println("-----------------------------------")
+	if n == 0 {
+		return fmt.Errorf("you don't follow a feed with URL %s", url)
+	}
+	if title == "" {
+		fmt.Printf("Feed %s shows its own name again\n", url)
+	} else {
+		fmt.Printf("Feed %s now shows as %s\n", url, title)
 	}
-
 	return nil
 }
 
-func scrapeFeeds(s *State) {
-
-	//get next feed to fetch
-	nextFeed, err := s.Db.GetNextFeedToFetch(context.Background())
+// HandlerDeleteFeed deletes a feed with its posts and follows. Only the
+// user who added it or an admin can delete it.
+func HandlerDeleteFeed(s *State, cmd Command, user database.User) error {
+	fs := newFlagSet("deletefeed")
+	yes := fs.Bool("yes", false, "delete without asking for confirmation")
+	args, err := parseArgs(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return errors.New("usage: deletefeed <url> [--yes]")
+	}
+	feed, err := s.Db.GetFeedByUrl(s.Context(), args[0])
 	if err != nil {
-		log.Println("Failed to grab next feed, err: %v", err)
-		return
+		return fmt.Errorf("feed with URL %s does not exist", args[0])
 	}
-	//mark it as fetched
-	log.Println("Fetching feed \n", nextFeed)
-	scrapeFeed(s.Db, nextFeed)
+	if !feed.UserID.Valid || feed.UserID.UUID != user.ID {
+		if err := RequireAdmin(user); err != nil {
+			return err
+		}
+	}
+	usage, err := s.Db.GetFeedUsage(s.Context(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't count posts and followers: %w", err)
+	}
+	fmt.Printf("Deleting %s also deletes its %d posts and %d follows\n", feed.Name, usage.Posts-usage.Starred, usage.Followers)
+	if usage.Starred > 0 {
+		fmt.Printf("%d starred posts are kept\n", usage.Starred)
+	}
+	if !*yes && !confirm(fmt.Sprintf("Delete feed %s?", feed.Name)) {
+		return errors.New("aborted, pass --yes to delete without confirmation")
+	}
+	if err := s.Db.DeleteFeed(s.Context(), feed.ID); err != nil {
+		return fmt.Errorf("couldn't delete feed: %w", err)
+	}
+	fmt.Printf("Feed %s deleted\n", feed.Name)
+	return nil
+}
 
+// postSummary is a post as listed by browse and starred.
+type postSummary struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedName    string
+	IsRead      bool
+	IsStarred   bool
+	IsMuted     bool
 }
 
-func scrapeFeed(db *database.Queries, feed database.Feed) {
-	_, err := db.MarkFeedFetched(context.Background(), feed.ID)
-	if err != nil {
-		log.Printf("failed to mark next feed, err: %v", err)
-		return
+func (post postSummary) record() postRecord {
+	return postRecord{
+		ID:          post.ID,
+		Title:       post.Title,
+		URL:         post.Url,
+		Description: nullString(post.Description),
+		PublishedAt: nullTime(post.PublishedAt),
+		FeedName:    post.FeedName,
+		Read:        post.IsRead,
+		Starred:     post.IsStarred,
+		Muted:       post.IsMuted,
 	}
-	returnedFeed, err := FetchFeed(context.Background(), feed.Url)
-	if err != nil {
-		log.Printf("failed to get feed, err: %v", err)
-		return
-	}
-	for _, object := range returnedFeed.Channel.Item {
-		publishedAt := sql.NullTime{}
-		if t, err := time.Parse(time.RFC1123Z, object.PubDate); err == nil {
-			publishedAt = sql.NullTime{
-				Time:  t,
-				Valid: true,
-			}
-		}
+}
 
-		_, err = db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:        uuid.New(),
-			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
-			FeedID:    feed.ID,
-			Title:     object.Title,
-			Description: sql.NullString{
-				String: object.Description,
-				Valid:  true,
-			},
-			Url:         object.Link,
-			PublishedAt: publishedAt,
-		})
-		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-				continue
-			}
-			log.Printf("Couldn't create post: %v", err)
-			continue
-		}
+func printPost(post postSummary) {
+	feedName := post.FeedName
+	if feedName == "" {
+		feedName = "a deleted feed"
+	}
+	status := ""
+	if !post.IsRead {
+		status += " (unread)"
+	}
+	if post.IsStarred {
+		status += " (starred)"
+	}
+	if post.IsMuted {
+		status += " (muted)"
 	}
-	log.Printf("Feed %s collected, %v posts found", feed.Name, len(returnedFeed.Channel.Item))
+	fmt.Printf("%s from %s%s\n", post.PublishedAt.Time.Format("Mon Jan 2"), stripControl(feedName), status)
+	fmt.Printf("--- %s ---\n", stripControl(post.Title))
+	fmt.Printf("    %v\n", stripControl(post.Description.String))
+	fmt.Printf("Link: %s\n", stripControl(post.Url))
+	fmt.Printf("ID: %s\n", post.ID)
+	fmt.Println("-----------------------------------")
 }
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// postVersion is one version of a post's text.
+type postVersion struct {
+	title       string
+	description sql.NullString
+	content     sql.NullString
+}
+
+func HandlerHistory(s *State, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: history <post-id>")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	revisions, err := s.Db.ListPostRevisions(s.Context(), post.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get post revisions: %w", err)
+	}
+
+	versions := make([]postVersion, 0, len(revisions)+1)
+	for _, r := range revisions {
+		versions = append(versions, postVersion{r.Title, r.Description, r.Content})
+	}
+	versions = append(versions, postVersion{post.Title, post.Description, post.Content})
+
+	if !s.Output.Text() {
+		records := make([]revisionRecord, 0, len(versions))
+		for i, v := range versions {
+			record := revisionRecord{
+				PostID:      post.ID,
+				Version:     i + 1,
+				Title:       v.title,
+				Description: nullString(v.description),
+				Content:     nullString(v.content),
+				Current:     i == len(revisions),
+			}
+			if i < len(revisions) {
+				record.ReplacedAt = &revisions[i].CreatedAt
+			}
+			records = append(records, record)
+		}
+		return printRecords(s, records)
+	}
+
+	fmt.Printf("--- %s --- from %s\n", stripControl(post.Title), stripControl(post.FeedName))
+	fmt.Printf("Link: %s\n", stripControl(post.Url))
+	if len(revisions) == 0 {
+		fmt.Println("No revisions, the post has not changed since it was collected")
+		return nil
+	}
+	fmt.Printf("%d revisions\n", len(revisions))
+
+	for i, r := range revisions {
+		fmt.Println("-----------------------------------")
+		fmt.Printf("Revision %d, changed %s\n", i+1, r.CreatedAt.Format(time.DateTime))
+		printVersionDiff(versions[i], versions[i+1])
+	}
+	return nil
+}
+
+func printVersionDiff(old, cur postVersion) {
+	printFieldDiff("Title", old.title, cur.title)
+	printFieldDiff("Description", old.description.String, cur.description.String)
+	printFieldDiff("Content", old.content.String, cur.content.String)
+}
+
+func printFieldDiff(field, old, cur string) {
+	if old == cur {
+		return
+	}
+	fmt.Printf("%s:\n", field)
+	for _, line := range diffLines(old, cur) {
+		fmt.Printf("    %s\n", stripControl(line))
+	}
+}
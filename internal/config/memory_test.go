@@ -0,0 +1,406 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gator/internal/database"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemFetcher is a Fetcher that serves canned feeds from memory, keyed by URL.
+// URLs listed in Errors fail with the given error instead.
+type MemFetcher struct {
+	mu     sync.Mutex
+	Feeds  map[string]*RSSFeed
+	Errors map[string]error
+	Calls  []string
+}
+
+func (f *MemFetcher) Fetch(ctx context.Context, feedURL string) (*RSSFeed, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Calls = append(f.Calls, feedURL)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err, ok := f.Errors[feedURL]; ok {
+		return nil, err
+	}
+	feed, ok := f.Feeds[feedURL]
+	if !ok {
+		return nil, fmt.Errorf("unexpected status code: %d", 404)
+	}
+	return feed, nil
+}
+
+var _ FeedStore = (*MemStore)(nil)
+
+// MemStore is an in-memory FeedStore. It mirrors the postgres queries closely
+// enough to exercise scheduling and duplicate handling without a database.
+type MemStore struct {
+	mu         sync.Mutex
+	Now        func() time.Time
+	Feeds      []database.Feed
+	Posts      []database.Post
+	Revisions  []database.PostRevision
+	Retention  map[uuid.UUID]database.FeedRetention
+	Default    database.DefaultRetention
+	Heartbeats map[string]database.AggregatorHeartbeat
+	Follows    []database.FeedFollow
+	PostStates []database.PostState
+	Alerts     map[string][]database.CreateAlertsRow
+	AlertErr   error
+}
+
+func (m *MemStore) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+func (m *MemStore) AddFeed(name, url string) database.Feed {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feed := database.Feed{
+		ID:        uuid.New(),
+		CreatedAt: m.now(),
+		UpdatedAt: m.now(),
+		Name:      name,
+		Url:       url,
+	}
+	m.Feeds = append(m.Feeds, feed)
+	return feed
+}
+
+// SetFetched records the feed at index i as last fetched, and so last
+// attempted, at t.
+func (m *MemStore) SetFetched(i int, t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Feeds[i].LastFetchedAt = sql.NullTime{Time: t, Valid: true}
+	m.Feeds[i].LastAttemptAt = sql.NullTime{Time: t, Valid: true}
+}
+
+func (m *MemStore) ClaimNextFeed(ctx context.Context, arg database.ClaimNextFeedParams) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	dueBefore := now.Add(-time.Duration(arg.DueAfterSeconds * float64(time.Second)))
+	next := -1
+	for i, feed := range m.Feeds {
+		if feed.ClaimedUntil.Valid && !feed.ClaimedUntil.Time.Before(now) {
+			continue
+		}
+		if feed.LastAttemptAt.Valid && !feed.LastAttemptAt.Time.Before(dueBefore) {
+			continue
+		}
+		if next == -1 || attemptedBefore(feed, m.Feeds[next]) {
+			next = i
+		}
+	}
+	if next == -1 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	m.Feeds[next].ClaimedBy = arg.ClaimedBy
+	m.Feeds[next].ClaimedUntil = sql.NullTime{
+		Time:  now.Add(time.Duration(arg.LeaseSeconds * float64(time.Second))),
+		Valid: true,
+	}
+	m.Feeds[next].LastAttemptAt = sql.NullTime{Time: now, Valid: true}
+	return m.Feeds[next], nil
+}
+
+func (m *MemStore) ReleaseFeed(ctx context.Context, arg database.ReleaseFeedParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.Feeds {
+		if m.Feeds[i].ID == arg.ID && m.Feeds[i].ClaimedBy == arg.ClaimedBy {
+			m.Feeds[i].ClaimedBy = sql.NullString{}
+			m.Feeds[i].ClaimedUntil = sql.NullTime{}
+		}
+	}
+	return nil
+}
+
+func (m *MemStore) CountDueFeeds(ctx context.Context, dueAfterSeconds float64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	dueBefore := now.Add(-time.Duration(dueAfterSeconds * float64(time.Second)))
+	var n int64
+	for _, feed := range m.Feeds {
+		if feed.ClaimedUntil.Valid && !feed.ClaimedUntil.Time.Before(now) {
+			continue
+		}
+		if !feed.LastAttemptAt.Valid || feed.LastAttemptAt.Time.Before(dueBefore) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// attemptedBefore orders feeds like "last_attempt_at ASC NULLS FIRST".
+func attemptedBefore(a, b database.Feed) bool {
+	if !a.LastAttemptAt.Valid {
+		return b.LastAttemptAt.Valid
+	}
+	return b.LastAttemptAt.Valid && a.LastAttemptAt.Time.Before(b.LastAttemptAt.Time)
+}
+
+func (m *MemStore) MarkFeedFetched(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.Feeds {
+		if m.Feeds[i].ID == id {
+			m.Feeds[i].LastFetchedAt = sql.NullTime{Time: m.now(), Valid: true}
+			m.Feeds[i].UpdatedAt = m.now()
+			return m.Feeds[i], nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+// WithinTx runs fn against m and restores feeds, posts and revisions to
+// their previous state if fn fails. It does not isolate concurrent callers.
+func (m *MemStore) WithinTx(ctx context.Context, fn func(tx FeedStore) error) error {
+	m.mu.Lock()
+	feeds, posts, revisions := slices.Clone(m.Feeds), slices.Clone(m.Posts), slices.Clone(m.Revisions)
+	m.mu.Unlock()
+	if err := fn(m); err != nil {
+		m.mu.Lock()
+		m.Feeds, m.Posts, m.Revisions = feeds, posts, revisions
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (m *MemStore) CreatePosts(ctx context.Context, arg database.CreatePostsParams) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var inserted []string
+	feedID := uuid.NullUUID{UUID: arg.FeedID, Valid: true}
+	var feedURL sql.NullString
+	if j := slices.IndexFunc(m.Feeds, func(f database.Feed) bool { return f.ID == arg.FeedID }); j != -1 {
+		feedURL = sql.NullString{String: m.Feeds[j].Url, Valid: true}
+	}
+	for i, url := range arg.Urls {
+		if j := slices.IndexFunc(m.Posts, func(p database.Post) bool { return p.Url == url }); j != -1 {
+			if !m.Posts[j].FeedID.Valid && m.Posts[j].FeedUrl.Valid && m.Posts[j].FeedUrl == feedURL {
+				// a starred post that outlived its feed
+				m.Posts[j].FeedID = feedID
+				inserted = append(inserted, url)
+			}
+			continue
+		}
+		publishedAt := sql.NullTime{}
+		if t, err := time.Parse(time.DateTime, arg.PublishedAts[i]); err == nil {
+			publishedAt = sql.NullTime{Time: t, Valid: true}
+		}
+		m.Posts = append(m.Posts, database.Post{
+			ID:          arg.Ids[i],
+			CreatedAt:   arg.CreatedAt,
+			UpdatedAt:   arg.CreatedAt,
+			Title:       arg.Titles[i],
+			Url:         url,
+			Description: sql.NullString{String: arg.Descriptions[i], Valid: true},
+			PublishedAt: publishedAt,
+			FeedID:      feedID,
+			FeedUrl:     feedURL,
+			Content:     nullIfEmpty(arg.Contents[i]),
+			Author:      nullIfEmpty(arg.Authors[i]),
+		})
+		inserted = append(inserted, url)
+	}
+	return inserted, nil
+}
+
+func (m *MemStore) UpdateChangedPosts(ctx context.Context, arg database.UpdateChangedPostsParams) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var updated []string
+	for i, url := range arg.Urls {
+		j := slices.IndexFunc(m.Posts, func(p database.Post) bool { return p.Url == url && p.FeedID.UUID == arg.FeedID && p.FeedID.Valid })
+		if j == -1 {
+			continue
+		}
+		post := m.Posts[j]
+		title := arg.Titles[i]
+		description := sql.NullString{String: arg.Descriptions[i], Valid: true}
+		content := nullIfEmpty(arg.Contents[i])
+		if post.Title == title && post.Description == description && post.Content == content {
+			continue
+		}
+		// content filled in on a post stored without any is no revision
+		revised := post.Title != title || post.Description != description || post.Content.Valid
+		if revised {
+			m.Revisions = append(m.Revisions, database.PostRevision{
+				ID:          uuid.New(),
+				CreatedAt:   m.now(),
+				PostID:      post.ID,
+				Title:       post.Title,
+				Description: post.Description,
+				Content:     post.Content,
+			})
+			post.UpdatedAt = m.now()
+			updated = append(updated, url)
+		}
+		post.Title = title
+		post.Description = description
+		post.Content = content
+		m.Posts[j] = post
+	}
+	return updated, nil
+}
+
+// CreateAlerts returns the canned alerts in Alerts for each of the given
+// URLs. Matching rules against posts is left to postgres.
+func (m *MemStore) CreateAlerts(ctx context.Context, arg database.CreateAlertsParams) ([]database.CreateAlertsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.AlertErr != nil {
+		return nil, m.AlertErr
+	}
+	var created []database.CreateAlertsRow
+	for _, url := range arg.Urls {
+		created = append(created, m.Alerts[url]...)
+	}
+	return created, nil
+}
+
+func (m *MemStore) PrunePosts(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	postedAt := func(p database.Post) time.Time {
+		if p.PublishedAt.Valid {
+			return p.PublishedAt.Time
+		}
+		return p.CreatedAt
+	}
+	var deleted []string
+	for _, feed := range m.Feeds {
+		maxAge, maxPosts, keepUnread := m.Default.MaxAgeSeconds, m.Default.MaxPosts, m.Default.KeepUnread
+		if r, ok := m.Retention[feed.ID]; ok {
+			if r.MaxAgeSeconds.Valid {
+				maxAge = r.MaxAgeSeconds.Float64
+			}
+			if r.MaxPosts.Valid {
+				maxPosts = r.MaxPosts.Int32
+			}
+			if r.KeepUnread.Valid {
+				keepUnread = r.KeepUnread.Bool
+			}
+		}
+		var posts []database.Post
+		for _, p := range m.Posts {
+			if p.FeedID.Valid && p.FeedID.UUID == feed.ID {
+				posts = append(posts, p)
+			}
+		}
+		slices.SortStableFunc(posts, func(a, b database.Post) int {
+			return postedAt(b).Compare(postedAt(a))
+		})
+		for i, p := range posts {
+			tooOld := maxAge > 0 && postedAt(p).Before(now.Add(-time.Duration(maxAge*float64(time.Second))))
+			tooMany := maxPosts > 0 && i >= int(maxPosts)
+			if (tooOld || tooMany) && !m.starred(p) && !(keepUnread && m.unreadByFollower(p)) {
+				m.Posts = slices.DeleteFunc(m.Posts, func(q database.Post) bool { return q.ID == p.ID })
+				deleted = append(deleted, feed.Name)
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// starred reports whether anyone starred p. m.mu must be held.
+func (m *MemStore) starred(p database.Post) bool {
+	return slices.ContainsFunc(m.PostStates, func(st database.PostState) bool {
+		return st.PostID == p.ID && st.StarredAt.Valid
+	})
+}
+
+func (m *MemStore) DeleteOrphanedPosts(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := len(m.Posts)
+	m.Posts = slices.DeleteFunc(m.Posts, func(p database.Post) bool {
+		return !p.FeedID.Valid && !m.starred(p)
+	})
+	return int64(before - len(m.Posts)), nil
+}
+
+// unreadByFollower reports whether anyone following p's feed hasn't read
+// it. m.mu must be held.
+func (m *MemStore) unreadByFollower(p database.Post) bool {
+	for _, follow := range m.Follows {
+		if !p.FeedID.Valid || follow.FeedID != p.FeedID.UUID {
+			continue
+		}
+		read := slices.ContainsFunc(m.PostStates, func(st database.PostState) bool {
+			return st.UserID == follow.UserID && st.PostID == p.ID && st.ReadAt.Valid
+		})
+		if !read {
+			return true
+		}
+	}
+	return false
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (m *MemStore) RecordHeartbeat(ctx context.Context, arg database.RecordHeartbeatParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Heartbeats == nil {
+		m.Heartbeats = make(map[string]database.AggregatorHeartbeat)
+	}
+	hb, ok := m.Heartbeats[arg.Instance]
+	if !ok {
+		hb = database.AggregatorHeartbeat{Instance: arg.Instance, StartedAt: m.now()}
+	}
+	hb.LastSeenAt = m.now()
+	hb.IntervalSeconds = arg.IntervalSeconds
+	hb.Once = arg.Once
+	if arg.Succeeded {
+		hb.LastSuccessAt = sql.NullTime{Time: m.now(), Valid: true}
+	}
+	hb.FeedsFetched = arg.FeedsFetched
+	hb.FeedsFailed = arg.FeedsFailed
+	hb.StoppedAt = sql.NullTime{}
+	m.Heartbeats[arg.Instance] = hb
+	return nil
+}
+
+func (m *MemStore) DeleteStaleHeartbeats(ctx context.Context, maxAgeSeconds float64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cutoff := m.now().Add(-time.Duration(maxAgeSeconds * float64(time.Second)))
+	var n int64
+	for instance, hb := range m.Heartbeats {
+		if hb.LastSeenAt.Before(cutoff) {
+			delete(m.Heartbeats, instance)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemStore) StopHeartbeat(ctx context.Context, instance string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hb, ok := m.Heartbeats[instance]; ok {
+		hb.StoppedAt = sql.NullTime{Time: m.now(), Valid: true}
+		hb.LastSeenAt = m.now()
+		m.Heartbeats[instance] = hb
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// muteFields are the post fields a mute rule can match, any matches all of
+// them.
+var muteFields = []string{"any", "title", "description", "author", "url"}
+
+// HandlerMute manages the user's mute rules. Posts matching a rule are left
+// out of browse and unread counts.
+//
+//	mute add [--field any|title|description|author|url] [--regex] [--feed URL] <pattern>
+//	mute list
+//	mute rm <id>
+//
+// Patterns match case insensitively, as a substring or with --regex as a
+// POSIX regular expression.
+func HandlerMute(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("usage: mute add [--field FIELD] [--regex] [--feed URL] <pattern> | list | rm <id>")
+	}
+	args := cmd.Args[1:]
+	switch cmd.Args[0] {
+	case "add":
+		return addMute(s, args, user)
+	case "list":
+		return listMutes(s, user)
+	case "rm":
+		return removeMute(s, args, user)
+	default:
+		return fmt.Errorf("unknown mute command: %s", cmd.Args[0])
+	}
+}
+
+func addMute(s *State, args []string, user database.User) error {
+	fs := newFlagSet("mute add")
+	field := fs.String("field", "any", "post field to match: "+strings.Join(muteFields, ", "))
+	regex := fs.Bool("regex", false, "match the pattern as a regular expression")
+	feedURL := fs.String("feed", "", "only mute posts of this feed")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	pattern := strings.Join(args, " ")
+	if pattern == "" {
+		return errors.New("usage: mute add [--field FIELD] [--regex] [--feed URL] <pattern>")
+	}
+	if !slices.Contains(muteFields, *field) {
+		return fmt.Errorf("unknown field %s, use one of %s", *field, strings.Join(muteFields, ", "))
+	}
+	if *regex {
+		// checked by postgres, which evaluates the rules
+		if err := s.Db.CheckRegex(s.Context(), pattern); err != nil {
+			return fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+	}
+	params := database.CreateMuteParams{UserID: user.ID, Field: *field, Pattern: pattern, IsRegex: *regex}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeedByUrl(s.Context(), *feedURL)
+		if err != nil {
+			return fmt.Errorf("feed with URL %s does not exist", *feedURL)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	mute, err := s.Db.CreateMute(s.Context(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't add mute rule: %w", err)
+	}
+	fmt.Printf("Muted %s (%s)\n", describeMute(mute.Field, mute.Pattern, mute.IsRegex, *feedURL), mute.ID)
+	return nil
+}
+
+func listMutes(s *State, user database.User) error {
+	mutes, err := s.Db.ListMutes(s.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't list mute rules: %w", err)
+	}
+	if !s.Output.Text() {
+		records := make([]muteRecord, 0, len(mutes))
+		for _, m := range mutes {
+			records = append(records, muteRecord{ID: m.ID, Field: m.Field, Pattern: m.Pattern, Regex: m.IsRegex, FeedURL: m.FeedUrl.String, Hidden: m.Hidden, CreatedAt: m.CreatedAt})
+		}
+		return printRecords(s, records)
+	}
+	if len(mutes) == 0 {
+		fmt.Println("No mute rules")
+		return nil
+	}
+	for _, m := range mutes {
+		fmt.Printf("%s\n  %s, hides %d posts\n", m.ID, describeMute(m.Field, m.Pattern, m.IsRegex, m.FeedUrl.String), m.Hidden)
+	}
+	return nil
+}
+
+func removeMute(s *State, args []string, user database.User) error {
+	if len(args) < 1 {
+		return errors.New("usage: mute rm <id>")
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid mute rule ID %q, see mute list", args[0])
+	}
+	n, err := s.Db.DeleteMute(s.Context(), database.DeleteMuteParams{ID: id, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("couldn't remove mute rule: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("mute rule %s does not exist", id)
+	}
+	fmt.Printf("Mute rule %s removed\n", id)
+	return nil
+}
+
+// describeMute says what a mute rule matches, e.g. title contains
+// "sponsored" in all feeds.
+func describeMute(field, pattern string, regex bool, feedURL string) string {
+	verb := "contains"
+	if regex {
+		verb = "matches"
+	}
+	if field == "any" {
+		field = "any field"
+	}
+	scope := "in all feeds"
+	if feedURL != "" {
+		scope = "in " + feedURL
+	}
+	return fmt.Sprintf("%s %s %q %s", field, verb, pattern, scope)
+}
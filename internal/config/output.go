@@ -0,0 +1,318 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutputFormat is how listing commands print their results, set with the
+// global --output flag. The zero value prints human readable text.
+type OutputFormat struct {
+	kind string
+	tmpl *template.Template
+}
+
+// ParseOutputFormat parses an --output value: text, json, csv, tsv or
+// template=TEMPLATE.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	if text, ok := strings.CutPrefix(s, "template="); ok {
+		tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return OutputFormat{}, fmt.Errorf("invalid output template: %w", err)
+		}
+		return OutputFormat{kind: "template", tmpl: tmpl}, nil
+	}
+	switch s {
+	case "", "text":
+		return OutputFormat{}, nil
+	case "json", "csv", "tsv":
+		return OutputFormat{kind: s}, nil
+	case "template":
+		return OutputFormat{}, fmt.Errorf("output format template needs a template, use --output 'template={{.name}}'")
+	default:
+		return OutputFormat{}, fmt.Errorf("unknown output format %q, use text, json, csv, tsv or template=TEMPLATE", s)
+	}
+}
+
+// Text reports whether results should be printed as human readable text.
+func (o OutputFormat) Text() bool {
+	return o.kind == ""
+}
+
+// writeRecords prints records, a slice of structs, in a machine readable
+// format. The json tags of the struct are the field names in every format.
+func writeRecords[T any](w io.Writer, o OutputFormat, records []T) error {
+	if records == nil {
+		// an empty list, not null
+		records = []T{}
+	}
+	switch o.kind {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if o.kind == "tsv" {
+			cw.Comma = '\t'
+		}
+		typ := reflect.TypeFor[T]()
+		header := make([]string, typ.NumField())
+		for i := range header {
+			header[i] = fieldName(typ.Field(i))
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, record := range records {
+			v := reflect.ValueOf(record)
+			row := make([]string, v.NumField())
+			for i := range row {
+				row[i] = formatField(v.Field(i))
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "template":
+		for _, record := range records {
+			// round trip through JSON so templates use the same field
+			// names as the other formats
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			var fields map[string]any
+			if err := json.Unmarshal(data, &fields); err != nil {
+				return err
+			}
+			for k, v := range fields {
+				if v == nil {
+					// print missing values as nothing, not <no value>
+					fields[k] = ""
+				}
+			}
+			var b strings.Builder
+			if err := o.tmpl.Execute(&b, fields); err != nil {
+				return fmt.Errorf("couldn't execute output template: %w", err)
+			}
+			out := b.String()
+			if !strings.HasSuffix(out, "\n") {
+				out += "\n"
+			}
+			if _, err := io.WriteString(w, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("output format %q can't print records", o.kind)
+}
+
+// printRecords writes records to stdout in the state's output format.
+func printRecords[T any](s *State, records []T) error {
+	return writeRecords(os.Stdout, s.Output, records)
+}
+
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// formatField formats a record field for csv and tsv. Missing values are
+// empty, times use the same format as JSON and lists are comma separated.
+func formatField(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case []string:
+		return strings.Join(x, ",")
+	case fmt.Stringer:
+		return x.String()
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// The records below are what listing commands print in the machine
+// readable formats. Their field names are part of gator's interface, add
+// fields rather than renaming them.
+
+type userRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Admin     bool      `json:"admin"`
+	Current   bool      `json:"current"`
+}
+
+type feedRecord struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	AddedBy string `json:"added_by"`
+}
+
+type followRecord struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	FeedName   string    `json:"feed_name"`
+	FeedURL    string    `json:"feed_url"`
+	Folder     string    `json:"folder"`
+	Tags       []string  `json:"tags"`
+	Unread     int64     `json:"unread"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type tagRecord struct {
+	Tag   string `json:"tag"`
+	Feeds int64  `json:"feeds"`
+}
+
+// postRecord is a post as listed by browse and starred. Cursor is only set
+// by browse, passing it to --after continues after the post.
+type postRecord struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description *string    `json:"description"`
+	PublishedAt *time.Time `json:"published_at"`
+	FeedName    string     `json:"feed_name"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+	Muted       bool       `json:"muted"`
+	Cursor      string     `json:"cursor,omitempty"`
+}
+
+type searchRecord struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	PublishedAt *time.Time `json:"published_at"`
+	FeedName    string     `json:"feed_name"`
+	Rank        float64    `json:"rank"`
+	Snippet     string     `json:"snippet"`
+}
+
+type muteRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Field     string    `json:"field"`
+	Pattern   string    `json:"pattern"`
+	Regex     bool      `json:"regex"`
+	FeedURL   string    `json:"feed_url"`
+	Hidden    int64     `json:"hidden"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// alertRecord is an alert as listed by alerts and passed to an alert
+// command. SeenAt is missing until the alert has been marked seen.
+type alertRecord struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	User      string     `json:"user"`
+	RuleID    uuid.UUID  `json:"rule_id"`
+	Pattern   string     `json:"pattern"`
+	PostID    uuid.UUID  `json:"post_id"`
+	Title     string     `json:"title"`
+	URL       string     `json:"url"`
+	FeedName  string     `json:"feed_name"`
+	SeenAt    *time.Time `json:"seen_at"`
+}
+
+type alertRuleRecord struct {
+	ID        uuid.UUID `json:"id"`
+	Field     string    `json:"field"`
+	Pattern   string    `json:"pattern"`
+	Regex     bool      `json:"regex"`
+	FeedURL   string    `json:"feed_url"`
+	Raised    int64     `json:"raised"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// revisionRecord is one version of a post as listed by history, oldest
+// first. ReplacedAt is when a newer version replaced it, missing for the
+// current version.
+type revisionRecord struct {
+	PostID      uuid.UUID  `json:"post_id"`
+	Version     int        `json:"version"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	Content     *string    `json:"content"`
+	ReplacedAt  *time.Time `json:"replaced_at"`
+	Current     bool       `json:"current"`
+}
+
+type heartbeatRecord struct {
+	Instance        string     `json:"instance"`
+	State           string     `json:"state"`
+	Once            bool       `json:"once"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastSeenAt      time.Time  `json:"last_seen_at"`
+	LastSuccessAt   *time.Time `json:"last_success_at"`
+	IntervalSeconds float64    `json:"interval_seconds"`
+	FeedsFetched    int32      `json:"feeds_fetched"`
+	FeedsFailed     int32      `json:"feeds_failed"`
+}
+
+// profileRecord is a profile as listed by profile list. DBURL has its
+// password removed.
+type profileRecord struct {
+	Name     string `json:"name"`
+	DBURL    string `json:"db_url"`
+	Current  bool   `json:"current"`
+	LoggedIn bool   `json:"logged_in"`
+}
+
+// retentionRecord is a retention policy as listed by retention show, first
+// the default and then each feed's override. Fields an override leaves to
+// the default are missing.
+type retentionRecord struct {
+	Default       bool     `json:"default"`
+	FeedName      string   `json:"feed_name"`
+	FeedURL       string   `json:"feed_url"`
+	MaxAgeSeconds *float64 `json:"max_age_seconds"`
+	MaxPosts      *int32   `json:"max_posts"`
+	KeepUnread    *bool    `json:"keep_unread"`
+}
+
+// prunableRecord is a post prune --dry-run would delete. FeedName is
+// (deleted feeds) for unstarred posts whose feed was deleted.
+type prunableRecord struct {
+	ID       uuid.UUID `json:"id"`
+	Title    string    `json:"title"`
+	FeedName string    `json:"feed_name"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
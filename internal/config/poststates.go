@@ -0,0 +1,210 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// lookupPost parses a post ID argument and fetches the post.
+func lookupPost(s *State, arg string) (database.GetPostRow, error) {
+	postID, err := uuid.Parse(arg)
+	if err != nil {
+		return database.GetPostRow{}, fmt.Errorf("invalid post id %q: %w", arg, err)
+	}
+	post, err := s.Db.GetPost(s.Context(), postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return post, fmt.Errorf("no post with id %s", postID)
+	}
+	if err != nil {
+		return post, fmt.Errorf("couldn't get post: %w", err)
+	}
+	return post, nil
+}
+
+// parseDate parses a date (2006-01-02, local time) or an RFC 3339 time and
+// returns it in UTC, which is how timestamps are stored.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD or RFC 3339", s)
+	}
+	return t.UTC(), nil
+}
+
+// parseDateRange parses optional --since and --until values. A plain date
+// as until includes the whole day.
+func parseDateRange(since, until string) (sql.NullTime, sql.NullTime, error) {
+	var from, to sql.NullTime
+	if since != "" {
+		t, err := parseDate(since)
+		if err != nil {
+			return from, to, err
+		}
+		from = sql.NullTime{Time: t, Valid: true}
+	}
+	if until != "" {
+		t, err := parseDate(until)
+		if err != nil {
+			return from, to, err
+		}
+		if len(until) == len(time.DateOnly) {
+			t = t.Add(24 * time.Hour)
+		}
+		to = sql.NullTime{Time: t, Valid: true}
+	}
+	return from, to, nil
+}
+
+func HandlerRead(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: read <post-id>")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	err = s.Db.MarkPostRead(s.Context(), database.MarkPostReadParams{UserID: user.ID, PostID: post.ID})
+	if err != nil {
+		return fmt.Errorf("couldn't mark post read: %w", err)
+	}
+	fmt.Printf("Marked %s as read\n", stripControl(post.Title))
+	return nil
+}
+
+func HandlerUnread(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: unread <post-id>")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	n, err := s.Db.MarkPostUnread(s.Context(), database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID})
+	if err != nil {
+		return fmt.Errorf("couldn't mark post unread: %w", err)
+	}
+	if n == 0 {
+		fmt.Printf("%s is already unread\n", stripControl(post.Title))
+		return nil
+	}
+	fmt.Printf("Marked %s as unread\n", stripControl(post.Title))
+	return nil
+}
+
+// HandlerMarkRead marks posts in followed feeds as read in bulk.
+//
+//	markread --feed URL | --before DATE | --all
+//
+// --feed and --before can be combined.
+func HandlerMarkRead(s *State, cmd Command, user database.User) error {
+	fs := newFlagSet("markread")
+	feedURL := fs.String("feed", "", "only mark posts of this feed")
+	before := fs.String("before", "", "only mark posts published before this date")
+	all := fs.Bool("all", false, "mark every post in the feeds you follow")
+	if _, err := parseArgs(fs, cmd.Args); err != nil {
+		return err
+	}
+	if *all == (*feedURL != "" || *before != "") {
+		return errors.New("usage: markread --feed URL | --before DATE | --all")
+	}
+
+	params := database.MarkPostsReadParams{UserID: user.ID}
+	if *feedURL != "" {
+		if _, err := s.Db.GetFeedByUrl(s.Context(), *feedURL); err != nil {
+			return fmt.Errorf("feed with URL %s does not exist", *feedURL)
+		}
+		params.FeedUrl = sql.NullString{String: *feedURL, Valid: true}
+	}
+	if *before != "" {
+		t, err := parseDate(*before)
+		if err != nil {
+			return err
+		}
+		params.Before = sql.NullTime{Time: t, Valid: true}
+	}
+	n, err := s.Db.MarkPostsRead(s.Context(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't mark posts read: %w", err)
+	}
+	fmt.Printf("Marked %d posts as read\n", n)
+	return nil
+}
+
+func HandlerStar(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: star <post-id>")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	err = s.Db.StarPost(s.Context(), database.StarPostParams{UserID: user.ID, PostID: post.ID})
+	if err != nil {
+		return fmt.Errorf("couldn't star post: %w", err)
+	}
+	fmt.Printf("Starred %s\n", stripControl(post.Title))
+	return nil
+}
+
+func HandlerUnstar(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: unstar <post-id>")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	n, err := s.Db.UnstarPost(s.Context(), database.UnstarPostParams{UserID: user.ID, PostID: post.ID})
+	if err != nil {
+		return fmt.Errorf("couldn't unstar post: %w", err)
+	}
+	if n == 0 {
+		fmt.Printf("%s is not starred\n", stripControl(post.Title))
+		return nil
+	}
+	fmt.Printf("Unstarred %s\n", stripControl(post.Title))
+	if !post.FeedID.Valid {
+		fmt.Println("Its feed was deleted, the post will be removed by the next prune unless someone else starred it")
+	}
+	return nil
+}
+
+func HandlerStarred(s *State, cmd Command, user database.User) error {
+	posts, err := s.Db.ListStarredPosts(s.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get starred posts: %w", err)
+	}
+	summaries := make([]postSummary, 0, len(posts))
+	for _, post := range posts {
+		summaries = append(summaries, postSummary{
+			ID:          post.ID,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedName:    post.FeedName,
+			IsRead:      post.IsRead,
+			IsStarred:   true,
+		})
+	}
+	if !s.Output.Text() {
+		records := make([]postRecord, 0, len(summaries))
+		for _, post := range summaries {
+			records = append(records, post.record())
+		}
+		return printRecords(s, records)
+	}
+	fmt.Printf("Found %d starred posts for user %s:\n", len(posts), user.Name)
+	for _, post := range summaries {
+		printPost(post)
+	}
+	return nil
+}
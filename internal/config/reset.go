@@ -0,0 +1,254 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// resetScope selects what reset deletes. With no table selected everything
+// goes, with user set only rows belonging to that user do.
+type resetScope struct {
+	posts   bool
+	feeds   bool
+	follows bool
+	user    *database.User
+}
+
+func (sc resetScope) all() bool {
+	return !sc.posts && !sc.feeds && !sc.follows
+}
+
+// describe says what the scope deletes, for the confirmation prompt.
+func (sc resetScope) describe() string {
+	if sc.all() {
+		if sc.user == nil {
+			return "all users, feeds, follows and posts"
+		}
+		return fmt.Sprintf("user %s, their follows with their tags, folders, mute and alert rules and the feeds they added with their posts", sc.user.Name)
+	}
+	var parts []string
+	if sc.posts {
+		if sc.user == nil {
+			parts = append(parts, "all posts")
+		} else {
+			parts = append(parts, "posts in feeds added by "+sc.user.Name)
+		}
+	}
+	if sc.feeds {
+		if sc.user == nil {
+			parts = append(parts, "all feeds with their posts and follows")
+		} else {
+			parts = append(parts, "feeds added by "+sc.user.Name+" with their posts and follows")
+		}
+	}
+	if sc.follows {
+		if sc.user == nil {
+			parts = append(parts, "all follows")
+		} else {
+			parts = append(parts, sc.user.Name+"'s follows")
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+// resetStep deletes the rows of one table and returns them as JSON.
+type resetStep struct {
+	table string
+	run   func(q *database.Queries, ctx context.Context, userID uuid.NullUUID) (string, error)
+}
+
+// steps lists the tables to delete from, children first so that nothing
+// is removed by a cascade without being backed up.
+func (sc resetScope) steps() []resetStep {
+	var steps []resetStep
+	if sc.all() || sc.posts || sc.feeds {
+		byUser := sc.all()
+		steps = append(steps,
+			resetStep{"post_states", func(q *database.Queries, ctx context.Context, userID uuid.NullUUID) (string, error) {
+				return q.ResetPostStates(ctx, database.ResetPostStatesParams{UserID: userID, ByUser: byUser, OfFeeds: true})
+			}},
+			resetStep{"alerts", func(q *database.Queries, ctx context.Context, userID uuid.NullUUID) (string, error) {
+				return q.ResetAlerts(ctx, database.ResetAlertsParams{UserID: userID, ByUser: byUser, OfFeeds: true})
+			}},
+			resetStep{"post_revisions", (*database.Queries).ResetPostRevisions},
+			resetStep{"posts", (*database.Queries).ResetPosts},
+		)
+	}
+	if sc.all() || sc.feeds || sc.follows {
+		byUser, ofFeeds := sc.all() || sc.follows, sc.all() || sc.feeds
+		steps = append(steps,
+			resetStep{"follow_tags", func(q *database.Queries, ctx context.Context, userID uuid.NullUUID) (string, error) {
+				return q.ResetFollowTags(ctx, database.ResetFollowTagsParams{UserID: userID, ByUser: byUser, OfFeeds: ofFeeds})
+			}},
+			resetStep{"feed_follows", func(q *database.Queries, ctx context.Context, userID uuid.NullUUID) (string, error) {
+				return q.ResetFeedFollows(ctx, database.ResetFeedFollowsParams{UserID: userID, ByUser: byUser, OfFeeds: ofFeeds})
+			}},
+		)
+	}
+	if sc.all() || sc.feeds {
+		byUser := sc.all()
+		steps = append(steps,
+			resetStep{"mutes", func(q *database.Queries, ctx context.Context, userID uuid.NullUUID) (string, error) {
+				return q.ResetMutes(ctx, database.ResetMutesParams{UserID: userID, ByUser: byUser, OfFeeds: true})
+			}},
+			resetStep{"alert_rules", func(q *database.Queries, ctx context.Context, userID uuid.NullUUID) (string, error) {
+				return q.ResetAlertRules(ctx, database.ResetAlertRulesParams{UserID: userID, ByUser: byUser, OfFeeds: true})
+			}},
+			resetStep{"feed_retention", (*database.Queries).ResetFeedRetention},
+			resetStep{"feeds", (*database.Queries).ResetFeeds},
+		)
+	}
+	if sc.all() {
+		steps = append(steps,
+			resetStep{"folders", (*database.Queries).ResetFolders},
+			resetStep{"sessions", (*database.Queries).ResetSessions},
+			resetStep{"users", (*database.Queries).ResetUsers},
+		)
+	}
+	return steps
+}
+
+// resetBackup is the file written before reset deletes anything. Tables
+// holds the deleted rows keyed by table name.
+type resetBackup struct {
+	CreatedAt time.Time                  `json:"created_at"`
+	Scope     string                     `json:"scope"`
+	Tables    map[string]json.RawMessage `json:"tables"`
+}
+
+// HandlerReset deletes data after asking for confirmation, writing the
+// deleted rows to a JSON backup first.
+//
+//	reset [--posts] [--feeds] [--follows] [--user NAME] [--backup-file PATH] [--yes]
+func HandlerReset(s *State, cmd Command, user database.User) error {
+	fs := newFlagSet("reset")
+	var sc resetScope
+	fs.BoolVar(&sc.posts, "posts", false, "delete posts")
+	fs.BoolVar(&sc.feeds, "feeds", false, "delete feeds with their posts and follows")
+	fs.BoolVar(&sc.follows, "follows", false, "delete follows")
+	userName := fs.String("user", "", "only delete rows belonging to this user")
+	backupFile := fs.String("backup-file", "", "where to write the backup")
+	yes := fs.Bool("yes", false, "delete without asking for confirmation")
+	if _, err := parseArgs(fs, cmd.Args); err != nil {
+		return err
+	}
+	var userID uuid.NullUUID
+	if *userName != "" {
+		target, err := s.Db.GetUser(s.Context(), *userName)
+		if err != nil {
+			return fmt.Errorf("user with name %s does not exist", *userName)
+		}
+		sc.user = &target
+		userID = uuid.NullUUID{UUID: target.ID, Valid: true}
+		if sc.all() && target.IsAdmin {
+			if err := checkNotLastAdmin(s); err != nil {
+				return err
+			}
+		}
+	}
+	if !*yes && !confirm(fmt.Sprintf("Delete %s?", sc.describe())) {
+		return errors.New("aborted, pass --yes to reset without confirmation")
+	}
+
+	path := *backupFile
+	if path == "" {
+		var err error
+		if path, err = defaultBackupPath(); err != nil {
+			return err
+		}
+	}
+	tx, err := s.Conn.BeginTx(s.Context(), nil)
+	if err != nil {
+		return fmt.Errorf("couldn't start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.Db.WithTx(tx)
+
+	backup := resetBackup{CreatedAt: time.Now().UTC(), Scope: sc.describe(), Tables: make(map[string]json.RawMessage)}
+	steps := sc.steps()
+	counts := make([]int, len(steps))
+	total := 0
+	for i, step := range steps {
+		deleted, err := step.run(q, s.Context(), userID)
+		if err != nil {
+			return fmt.Errorf("couldn't delete %s: %w", step.table, err)
+		}
+		var rows []json.RawMessage
+		if err := json.Unmarshal([]byte(deleted), &rows); err != nil {
+			return fmt.Errorf("couldn't read deleted %s: %w", step.table, err)
+		}
+		backup.Tables[step.table] = json.RawMessage(deleted)
+		counts[i] = len(rows)
+		total += len(rows)
+	}
+	if total == 0 {
+		fmt.Println("Nothing to delete")
+		return nil
+	}
+
+	// the deletes are only committed once the backup is safely on disk
+	if err := writeBackup(path, backup); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("couldn't commit reset: %w", err)
+	}
+	fmt.Printf("Backup written to %s\n", path)
+	for i, step := range steps {
+		if counts[i] > 0 {
+			fmt.Printf("%s: %d rows deleted\n", step.table, counts[i])
+		}
+	}
+	fmt.Printf("Deleted %d rows\n", total)
+
+	if sc.all() && (sc.user == nil || sc.user.ID == user.ID) {
+		// the session was deleted with the user
+		s.Profile.SessionToken = ""
+	}
+	return nil
+}
+
+// defaultBackupPath returns a new file name under ~/.gator/backups.
+func defaultBackupPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("couldn't find home directory for backup: %w", err)
+	}
+	name := "reset-" + time.Now().UTC().Format("20060102-150405") + ".json"
+	return filepath.Join(home, ".gator", "backups", name), nil
+}
+
+func writeBackup(path string, backup resetBackup) error {
+	data, err := json.MarshalIndent(backup, "", " ")
+	if err != nil {
+		return fmt.Errorf("couldn't encode backup: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("couldn't create backup directory: %w", err)
+	}
+	// O_EXCL so an earlier backup is never overwritten
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("couldn't write backup: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("couldn't write backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("couldn't write backup: %w", err)
+	}
+	return nil
+}
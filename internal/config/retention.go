@@ -0,0 +1,304 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"gator/internal/database"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseAge parses a duration that may also be given in days, e.g. "90d".
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// formatAge formats d in whole days when it is a multiple of a day.
+func formatAge(d time.Duration) string {
+	if d == 0 {
+		return "unlimited"
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+func formatMaxPosts(n int) string {
+	if n == 0 {
+		return "unlimited"
+	}
+	return strconv.Itoa(n)
+}
+
+// pruneStore is the storage needed to apply retention policies.
+type pruneStore interface {
+	PrunePosts(ctx context.Context) ([]string, error)
+	DeleteOrphanedPosts(ctx context.Context) (int64, error)
+}
+
+// orphanedFeedName is the key prune counts posts of deleted feeds under.
+const orphanedFeedName = "(deleted feeds)"
+
+// prune deletes every post outside its feed's retention policy, falling
+// back to the default policy for feeds without an override, and the posts
+// of deleted feeds that are no longer starred. It returns the number of
+// deleted posts per feed name.
+func prune(ctx context.Context, store pruneStore) (map[string]int, error) {
+	feeds, err := store.PrunePosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, feed := range feeds {
+		counts[feed]++
+	}
+	orphaned, err := store.DeleteOrphanedPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if orphaned > 0 {
+		counts[orphanedFeedName] = int(orphaned)
+	}
+	return counts, nil
+}
+
+func HandlerPrune(s *State, cmd Command, _ database.User) error {
+	fs := newFlagSet("prune")
+	dryRun := fs.Bool("dry-run", false, "list the posts that would be deleted without deleting them")
+	if _, err := parseArgs(fs, cmd.Args); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		posts, err := s.Db.ListPrunablePosts(s.Context())
+		if err != nil {
+			return fmt.Errorf("couldn't list posts to prune: %w", err)
+		}
+		orphaned, err := s.Db.ListOrphanedPosts(s.Context())
+		if err != nil {
+			return fmt.Errorf("couldn't list posts of deleted feeds: %w", err)
+		}
+		for _, post := range orphaned {
+			posts = append(posts, database.ListPrunablePostsRow{ID: post.ID, Title: post.Title, FeedName: orphanedFeedName, PostedAt: post.PostedAt})
+		}
+		if !s.Output.Text() {
+			records := make([]prunableRecord, 0, len(posts))
+			for _, post := range posts {
+				records = append(records, prunableRecord{ID: post.ID, Title: post.Title, FeedName: post.FeedName, PostedAt: post.PostedAt})
+			}
+			return printRecords(s, records)
+		}
+		if len(posts) == 0 {
+			fmt.Println("Nothing to prune")
+			return nil
+		}
+		fmt.Printf("Would delete %d posts:\n", len(posts))
+		for _, post := range posts {
+			fmt.Printf("%s from %s: %s (%s)\n", post.PostedAt.Format("Mon Jan 2 2006"), stripControl(post.FeedName), stripControl(post.Title), post.ID)
+		}
+		return nil
+	}
+
+	counts, err := prune(s.Context(), s.Db)
+	if err != nil {
+		return fmt.Errorf("couldn't prune posts: %w", err)
+	}
+	printPruneCounts(counts)
+	return nil
+}
+
+func printPruneCounts(counts map[string]int) {
+	if len(counts) == 0 {
+		fmt.Println("Nothing to prune")
+		return
+	}
+	total := 0
+	for _, feed := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Printf("%s: %d posts deleted\n", feed, counts[feed])
+		total += counts[feed]
+	}
+	fmt.Printf("Deleted %d posts\n", total)
+}
+
+// HandlerRetention shows and changes retention policies. Only admins can
+// change them.
+//
+//	retention show
+//	retention set (<feed_url> | --default) [--max-age 90d] [--max-posts N] [--keep-unread[=false]]
+//	retention clear <feed_url>
+func HandlerRetention(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("usage: retention show | set (<feed_url> | --default) [--max-age 90d] [--max-posts N] | clear <feed_url>")
+	}
+	switch cmd.Args[0] {
+	case "show":
+		return showRetention(s)
+	case "set":
+		if err := RequireAdmin(user); err != nil {
+			return err
+		}
+		return setRetention(s, cmd.Args[1:])
+	case "clear":
+		if err := RequireAdmin(user); err != nil {
+			return err
+		}
+		if len(cmd.Args) < 2 {
+			return errors.New("usage: retention clear <feed_url>")
+		}
+		return clearRetention(s, cmd.Args[1])
+	default:
+		return fmt.Errorf("unknown retention command: %s", cmd.Args[0])
+	}
+}
+
+func showRetention(s *State) error {
+	policy, err := s.Db.GetDefaultRetention(s.Context())
+	if err != nil {
+		return fmt.Errorf("couldn't get the default retention policy: %w", err)
+	}
+	overrides, err := s.Db.ListFeedRetention(s.Context())
+	if err != nil {
+		return fmt.Errorf("couldn't list feed retention: %w", err)
+	}
+	if !s.Output.Text() {
+		records := []retentionRecord{{
+			Default:       true,
+			MaxAgeSeconds: &policy.MaxAgeSeconds,
+			MaxPosts:      &policy.MaxPosts,
+			KeepUnread:    &policy.KeepUnread,
+		}}
+		for _, o := range overrides {
+			record := retentionRecord{FeedName: o.FeedName, FeedURL: o.FeedUrl}
+			if o.MaxAgeSeconds.Valid {
+				record.MaxAgeSeconds = &o.MaxAgeSeconds.Float64
+			}
+			if o.MaxPosts.Valid {
+				record.MaxPosts = &o.MaxPosts.Int32
+			}
+			if o.KeepUnread.Valid {
+				record.KeepUnread = &o.KeepUnread.Bool
+			}
+			records = append(records, record)
+		}
+		return printRecords(s, records)
+	}
+
+	fmt.Printf("Default: max age %s, max posts per feed %s, keep unread %t\n", formatAge(secondsToDuration(policy.MaxAgeSeconds)), formatMaxPosts(int(policy.MaxPosts)), policy.KeepUnread)
+	for _, o := range overrides {
+		age, posts, unread := "default", "default", "default"
+		if o.MaxAgeSeconds.Valid {
+			age = formatAge(secondsToDuration(o.MaxAgeSeconds.Float64))
+		}
+		if o.MaxPosts.Valid {
+			posts = formatMaxPosts(int(o.MaxPosts.Int32))
+		}
+		if o.KeepUnread.Valid {
+			unread = strconv.FormatBool(o.KeepUnread.Bool)
+		}
+		fmt.Printf("%s (%s): max age %s, max posts %s, keep unread %s\n", o.FeedName, o.FeedUrl, age, posts, unread)
+	}
+	return nil
+}
+
+func setRetention(s *State, args []string) error {
+	fs := newFlagSet("retention set")
+	isDefault := fs.Bool("default", false, "change the default policy instead of a feed's")
+	maxAge := fs.String("max-age", "", "delete posts older than this, e.g. 90d, 0 for unlimited")
+	maxPosts := fs.Int("max-posts", 0, "keep at most this many posts per feed, 0 for unlimited")
+	keepUnread := fs.Bool("keep-unread", false, "never delete posts a follower hasn't read")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	setMaxPosts, setKeepUnread := false, false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-posts":
+			setMaxPosts = true
+		case "keep-unread":
+			setKeepUnread = true
+		}
+	})
+	if *maxAge == "" && !setMaxPosts && !setKeepUnread {
+		return errors.New("set --max-age, --max-posts and/or --keep-unread")
+	}
+	if *maxPosts < 0 {
+		return fmt.Errorf("--max-posts must be 0 or more, got %d", *maxPosts)
+	}
+	var age time.Duration
+	if *maxAge != "" {
+		if age, err = parseAge(*maxAge); err != nil {
+			return err
+		}
+	}
+
+	if *isDefault {
+		_, err := s.Db.SetDefaultRetention(s.Context(), database.SetDefaultRetentionParams{
+			MaxAgeSeconds: sql.NullFloat64{Float64: age.Seconds(), Valid: *maxAge != ""},
+			MaxPosts:      sql.NullInt32{Int32: int32(*maxPosts), Valid: setMaxPosts},
+			KeepUnread:    sql.NullBool{Bool: *keepUnread, Valid: setKeepUnread},
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't set the default retention policy: %w", err)
+		}
+		fmt.Println("Default retention policy updated")
+		return nil
+	}
+
+	if len(args) < 1 {
+		return errors.New("feed URL or --default required")
+	}
+	feed, err := s.Db.GetFeedByUrl(s.Context(), args[0])
+	if err != nil {
+		return fmt.Errorf("failed to retrieve feed, error: %v", err)
+	}
+	err = s.Db.SetFeedRetention(s.Context(), database.SetFeedRetentionParams{
+		FeedID:        feed.ID,
+		MaxAgeSeconds: sql.NullFloat64{Float64: age.Seconds(), Valid: *maxAge != ""},
+		MaxPosts:      sql.NullInt32{Int32: int32(*maxPosts), Valid: setMaxPosts},
+		KeepUnread:    sql.NullBool{Bool: *keepUnread, Valid: setKeepUnread},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't set retention for %s: %w", feed.Name, err)
+	}
+	fmt.Printf("Retention policy for %s updated\n", feed.Name)
+	return nil
+}
+
+func clearRetention(s *State, url string) error {
+	feed, err := s.Db.GetFeedByUrl(s.Context(), url)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve feed, error: %v", err)
+	}
+	n, err := s.Db.DeleteFeedRetention(s.Context(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't clear retention for %s: %w", feed.Name, err)
+	}
+	if n == 0 {
+		fmt.Printf("%s already uses the default retention policy\n", feed.Name)
+		return nil
+	}
+	fmt.Printf("%s now uses the default retention policy\n", feed.Name)
+	return nil
+}
@@ -17,4 +17,16 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	PubDate     string `xml:"pubDate"`
 	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+// author returns the item's dc:creator, which most feeds use for a name,
+// falling back to the RSS author field.
+func (item RSSItem) author() string {
+	if item.Creator != "" {
+		return item.Creator
+	}
+	return item.Author
 }
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// HandlerSearch runs a full-text search over posts, by default only in the
+// feeds the user follows.
+//
+//	search [--feed URL] [--since DATE] [--until DATE] [--all] [--limit N] <query>
+//
+// The query supports "quoted phrases", -excluded words and or. Put it after
+// -- when it starts with an exclusion.
+func HandlerSearch(s *State, cmd Command, user database.User) error {
+	fs := newFlagSet("search")
+	feedURL := fs.String("feed", "", "only search posts of this feed")
+	since := fs.String("since", "", "only posts published on or after this date")
+	until := fs.String("until", "", "only posts published before the end of this date")
+	all := fs.Bool("all", false, "search every feed, not just the ones you follow")
+	limit := fs.Int("limit", 10, "maximum number of results")
+	args, err := parseArgs(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if err := checkLimit(*limit); err != nil {
+		return err
+	}
+	query := strings.TrimSpace(strings.Join(args, " "))
+	if query == "" {
+		return errors.New("usage: search [--feed URL] [--since DATE] [--until DATE] [--all] [--limit N] <query>")
+	}
+
+	params := database.SearchPostsParams{
+		Query:    query,
+		AllFeeds: *all,
+		UserID:   user.ID,
+		PageSize: int32(*limit),
+	}
+	if *feedURL != "" {
+		params.FeedUrl = sql.NullString{String: *feedURL, Valid: true}
+	}
+	if params.Since, params.Until, err = parseDateRange(*since, *until); err != nil {
+		return err
+	}
+
+	results, err := s.Db.SearchPosts(s.Context(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't search posts: %w", err)
+	}
+	if !s.Output.Text() {
+		records := make([]searchRecord, 0, len(results))
+		for _, r := range results {
+			snippet := strings.NewReplacer("\x02", "", "\x03", "").Replace(cleanSnippet(r.Snippet))
+			records = append(records, searchRecord{ID: r.ID, Title: r.Title, URL: r.Url, PublishedAt: nullTime(r.PublishedAt), FeedName: r.FeedName, Rank: r.Rank, Snippet: snippet})
+		}
+		return printRecords(s, records)
+	}
+	if len(results) == 0 {
+		fmt.Println("No posts found")
+		return nil
+	}
+	bold, reset := "**", "**"
+	if isTerminal(os.Stdout) {
+		bold, reset = "\x1b[1m", "\x1b[0m"
+	}
+	for i, r := range results {
+		fmt.Printf("%d. %s\n", i+1, stripControl(r.Title))
+		fmt.Printf("   %s from %s (rank %.2f)\n", r.PublishedAt.Time.Format("Mon Jan 2 2006"), stripControl(r.FeedName), r.Rank)
+		if snippet := cleanSnippet(r.Snippet); snippet != "" {
+			snippet = strings.NewReplacer("\x02", bold, "\x03", reset).Replace(snippet)
+			fmt.Printf("   %s\n", snippet)
+		}
+		fmt.Printf("   Link: %s\n", stripControl(r.Url))
+		fmt.Printf("   ID: %s\n", r.ID)
+	}
+	return nil
+}
+
+var (
+	htmlTag    = regexp.MustCompile(`<[^>]*>`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// cleanSnippet turns a search headline into a single line of plain text,
+// keeping the \x02 and \x03 match markers but dropping any other control
+// characters, see stripControl.
+func cleanSnippet(s string) string {
+	s = htmlTag.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	s = strings.Map(func(r rune) rune {
+		if isControl(r) && r != '\x02' && r != '\x03' {
+			return -1
+		}
+		return r
+	}, whitespace.ReplaceAllString(s, " "))
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,19 @@
+package config
+
+import "testing"
+
+func TestCleanSnippet(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<p>a \x02match\x03 here</p>", "a \x02match\x03 here"},
+		{"line\none &amp; two", "line one & two"},
+		{"\x1b]0;pwned\x07 \x02hit\x03", "]0;pwned \x02hit\x03"},
+		{"&#27;[2J\x02hit\x03", "[2J\x02hit\x03"},
+	}
+	for _, tt := range tests {
+		if got := cleanSnippet(tt.in); got != tt.want {
+			t.Errorf("cleanSnippet(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"gator/internal/database"
+)
+
+// dbStore is the postgres FeedStore.
+type dbStore struct {
+	*database.Queries
+	db *sql.DB
+}
+
+func (s dbStore) WithinTx(ctx context.Context, fn func(tx FeedStore) error) error {
+	if s.db == nil {
+		// already inside a transaction
+		return fn(s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(dbStore{Queries: s.Queries.WithTx(tx)}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// HandlerTag labels the feeds the user follows with tags. Unlike folders a
+// feed can have any number of tags, and tags need not be created first.
+//
+//	tag add <feed_url> <tag>...
+//	tag rm <feed_url> <tag>...
+//	tag list
+func HandlerTag(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("usage: tag add <feed_url> <tag>... | rm <feed_url> <tag>... | list")
+	}
+	args := cmd.Args[1:]
+	switch cmd.Args[0] {
+	case "add":
+		return addTags(s, args, user)
+	case "rm":
+		return removeTags(s, args, user)
+	case "list":
+		return listTags(s, user)
+	default:
+		return fmt.Errorf("unknown tag command: %s", cmd.Args[0])
+	}
+}
+
+// checkTag rejects tags that would be ambiguous where tags are printed as a
+// comma separated list.
+func checkTag(tag string) error {
+	if tag == "" || strings.ContainsFunc(tag, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		return fmt.Errorf("invalid tag %q, tags can't be empty or contain commas or spaces", tag)
+	}
+	return nil
+}
+
+func addTags(s *State, args []string, user database.User) error {
+	if len(args) < 2 {
+		return errors.New("usage: tag add <feed_url> <tag>...")
+	}
+	for _, tag := range args[1:] {
+		if err := checkTag(tag); err != nil {
+			return err
+		}
+	}
+	for _, tag := range args[1:] {
+		followed, err := s.Db.AddFollowTag(s.Context(), database.AddFollowTagParams{UserID: user.ID, FeedUrl: args[0], Tag: tag})
+		if err != nil {
+			return fmt.Errorf("couldn't tag feed: %w", err)
+		}
+		if !followed {
+			return fmt.Errorf("you don't follow a feed with URL %s", args[0])
+		}
+	}
+	fmt.Printf("Feed %s tagged %s\n", args[0], strings.Join(args[1:], ", "))
+	return nil
+}
+
+func removeTags(s *State, args []string, user database.User) error {
+	if len(args) < 2 {
+		return errors.New("usage: tag rm <feed_url> <tag>...")
+	}
+	for _, tag := range args[1:] {
+		n, err := s.Db.RemoveFollowTag(s.Context(), database.RemoveFollowTagParams{UserID: user.ID, FeedUrl: args[0], Tag: tag})
+		if err != nil {
+			return fmt.Errorf("couldn't remove tag %s: %w", tag, err)
+		}
+		if n == 0 {
+			return fmt.Errorf("feed %s is not tagged %s", args[0], tag)
+		}
+	}
+	fmt.Printf("Removed %s from feed %s\n", strings.Join(args[1:], ", "), args[0])
+	return nil
+}
+
+func listTags(s *State, user database.User) error {
+	tags, err := s.Db.ListTags(s.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't list tags: %w", err)
+	}
+	if !s.Output.Text() {
+		records := make([]tagRecord, 0, len(tags))
+		for _, t := range tags {
+			records = append(records, tagRecord{Tag: t.Tag, Feeds: t.Feeds})
+		}
+		return printRecords(s, records)
+	}
+	if len(tags) == 0 {
+		fmt.Println("No tags, add one with tag add <feed_url> <tag>")
+		return nil
+	}
+	for _, t := range tags {
+		fmt.Printf("%s (%d feeds)\n", t.Tag, t.Feeds)
+	}
+	return nil
+}
+
+// formatTags formats a follow's tags to follow its name in a listing.
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(tags, ", ") + "]"
+}
+
+// lookupTag checks that the user has tagged at least one feed with tag.
+func lookupTag(s *State, user database.User, tag string) error {
+	tags, err := s.Db.ListTags(s.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't list tags: %w", err)
+	}
+	if !slices.ContainsFunc(tags, func(t database.ListTagsRow) bool { return t.Tag == tag }) {
+		return fmt.Errorf("no feed is tagged %s", tag)
+	}
+	return nil
+}
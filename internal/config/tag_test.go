@@ -0,0 +1,16 @@
+package config
+
+import "testing"
+
+func TestCheckTag(t *testing.T) {
+	for _, tag := range []string{"go", "self-hosted", "ünïcödé"} {
+		if err := checkTag(tag); err != nil {
+			t.Errorf("checkTag(%q) = %v, want nil", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "two words", "a,b", "tab\there"} {
+		if err := checkTag(tag); err == nil {
+			t.Errorf("checkTag(%q) accepted an invalid tag", tag)
+		}
+	}
+}
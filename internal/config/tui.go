@@ -0,0 +1,666 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"html"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tuiPageSize is how many posts the reader loads at a time. More are
+// fetched by keyset when the selection reaches the end of the list.
+const tuiPageSize = 100
+
+const tuiHelp = "j/k move  n/p post  tab pane  enter open  space/b scroll  m read  s star  o link  u unread  r refresh  q quit"
+
+type tuiPane int
+
+const (
+	feedPane tuiPane = iota
+	postPane
+	articlePane
+)
+
+// tui is the state of the terminal reader.
+type tui struct {
+	s    *State
+	user database.User
+	out  *bufio.Writer
+
+	feeds []database.GetFeedFollowsForUserRow
+	// feed is the selected line in the feed pane, 0 is all feeds and i is
+	// feeds[i-1]
+	feed    int
+	feedTop int
+
+	posts      []database.BrowsePostsRow
+	post       int
+	postTop    int
+	more       bool
+	unreadOnly bool
+
+	article    []string
+	articleTop int
+
+	focus  tuiPane
+	status string
+	rows   int
+	cols   int
+}
+
+// HandlerTui runs a full-screen reader for the feeds the user follows.
+//
+//	tui
+func HandlerTui(s *State, cmd Command, user database.User) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return errors.New("tui needs a terminal")
+	}
+	t := &tui{s: s, user: user, out: bufio.NewWriter(os.Stdout), focus: postPane}
+	if err := t.loadFeeds(); err != nil {
+		return err
+	}
+	if err := t.loadPosts(); err != nil {
+		return err
+	}
+	restore, err := rawMode()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return t.run()
+}
+
+// rawMode switches the terminal to raw mode on the alternate screen and
+// returns a function that switches it back.
+func rawMode() (func(), error) {
+	cmd := exec.Command("stty", "-g")
+	cmd.Stdin = os.Stdin
+	saved, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read terminal settings: %w", err)
+	}
+	if err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("couldn't switch terminal to raw mode: %w", err)
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	return func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		stty(strings.TrimSpace(string(saved)))
+	}, nil
+}
+
+// terminalSize returns the rows and columns of the terminal, falling back
+// to 24x80.
+func terminalSize() (int, int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err == nil {
+		var rows, cols int
+		if _, err := fmt.Sscan(string(out), &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+func (t *tui) run() error {
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+	t.resize()
+
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := stdinReader.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			for _, key := range splitKeys(string(buf[:n])) {
+				keys <- key
+			}
+		}
+	}()
+	for {
+		t.draw()
+		select {
+		case <-t.s.Context().Done():
+			return nil
+		case <-resized:
+			t.resize()
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			quit, err := t.handleKey(key)
+			if err != nil {
+				t.status = err.Error()
+			}
+			if quit {
+				return nil
+			}
+		}
+	}
+}
+
+// splitKeys splits what was read from the terminal into keys, keeping
+// escape sequences for arrows and page up/down together.
+func splitKeys(in string) []string {
+	var keys []string
+	for len(in) > 0 {
+		if in[0] == '\x1b' && len(in) >= 3 && in[1] == '[' {
+			end := 2
+			for end < len(in) && (in[end] < 0x40 || in[end] > 0x7e) {
+				end++
+			}
+			if end < len(in) {
+				end++
+			}
+			keys = append(keys, in[:end])
+			in = in[end:]
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(in)
+		keys = append(keys, in[:size])
+		in = in[size:]
+	}
+	return keys
+}
+
+func (t *tui) handleKey(key string) (bool, error) {
+	t.status = ""
+	switch key {
+	case "q", "\x03":
+		return true, nil
+	case "\t":
+		t.focus = (t.focus + 1) % 3
+	case "j", "\x1b[B":
+		return false, t.move(1)
+	case "k", "\x1b[A":
+		return false, t.move(-1)
+	case "n":
+		return false, t.selectPost(t.post + 1)
+	case "p":
+		return false, t.selectPost(t.post - 1)
+	case "\r", "\n":
+		switch t.focus {
+		case feedPane:
+			t.focus = postPane
+		case postPane:
+			t.focus = articlePane
+			return false, t.setRead(true)
+		}
+	case " ", "\x1b[6~":
+		t.scrollArticle(t.articleHeight() - 1)
+	case "b", "\x1b[5~":
+		t.scrollArticle(-(t.articleHeight() - 1))
+	case "m":
+		if post := t.current(); post != nil {
+			return false, t.setRead(!post.IsRead)
+		}
+	case "s":
+		return false, t.toggleStar()
+	case "o":
+		return false, t.openLink()
+	case "u":
+		t.unreadOnly = !t.unreadOnly
+		return false, t.loadPosts()
+	case "r":
+		if err := t.loadFeeds(); err != nil {
+			return false, err
+		}
+		if err := t.loadPosts(); err != nil {
+			return false, err
+		}
+		t.status = "Refreshed"
+	}
+	return false, nil
+}
+
+func (t *tui) move(delta int) error {
+	switch t.focus {
+	case feedPane:
+		feed := min(max(t.feed+delta, 0), len(t.feeds))
+		if feed != t.feed {
+			t.feed = feed
+			return t.loadPosts()
+		}
+	case postPane:
+		return t.selectPost(t.post + delta)
+	case articlePane:
+		t.scrollArticle(delta)
+	}
+	return nil
+}
+
+func (t *tui) current() *database.BrowsePostsRow {
+	if t.post < 0 || t.post >= len(t.posts) {
+		return nil
+	}
+	return &t.posts[t.post]
+}
+
+func (t *tui) loadFeeds() error {
+	feeds, err := t.s.Db.GetFeedFollowsForUser(t.s.Context(), t.user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get followed feeds: %w", err)
+	}
+	t.feeds = feeds
+	t.feed = min(t.feed, len(feeds))
+	return nil
+}
+
+// loadPosts loads the first page of posts of the selected feed, keeping
+// the selected post when it is still listed.
+func (t *tui) loadPosts() error {
+	selected := t.current()
+	params := database.BrowsePostsParams{UserID: t.user.ID, UnreadOnly: t.unreadOnly, PageSize: tuiPageSize}
+	if t.feed > 0 {
+		params.FeedUrl.String, params.FeedUrl.Valid = t.feeds[t.feed-1].FeedUrl, true
+	}
+	posts, err := t.s.Db.BrowsePosts(t.s.Context(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't get posts: %w", err)
+	}
+	post := 0
+	if selected != nil {
+		for i, p := range posts {
+			if p.ID == selected.ID {
+				post = i
+			}
+		}
+	}
+	t.posts, t.more = posts, len(posts) == tuiPageSize
+	t.post, t.postTop = -1, 0
+	t.article, t.articleTop = nil, 0
+	return t.selectPost(post)
+}
+
+// loadMore appends the next page of posts after the last one listed.
+func (t *tui) loadMore() error {
+	last := t.posts[len(t.posts)-1]
+	params := database.BrowsePostsParams{UserID: t.user.ID, UnreadOnly: t.unreadOnly, PageSize: tuiPageSize}
+	if t.feed > 0 {
+		params.FeedUrl.String, params.FeedUrl.Valid = t.feeds[t.feed-1].FeedUrl, true
+	}
+	params.AfterPostedAt.Time, params.AfterPostedAt.Valid = last.PostedAt, true
+	params.AfterID = last.ID
+	posts, err := t.s.Db.BrowsePosts(t.s.Context(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't get posts: %w", err)
+	}
+	t.posts, t.more = append(t.posts, posts...), len(posts) == tuiPageSize
+	return nil
+}
+
+func (t *tui) selectPost(post int) error {
+	if post >= len(t.posts)-1 && t.more {
+		if err := t.loadMore(); err != nil {
+			return err
+		}
+	}
+	post = min(max(post, 0), len(t.posts)-1)
+	if post == t.post {
+		return nil
+	}
+	t.post = post
+	t.article, t.articleTop = nil, 0
+	return nil
+}
+
+func (t *tui) setRead(read bool) error {
+	post := t.current()
+	if post == nil || post.IsRead == read {
+		return nil
+	}
+	var err error
+	if read {
+		err = t.s.Db.MarkPostRead(t.s.Context(), database.MarkPostReadParams{UserID: t.user.ID, PostID: post.ID})
+	} else {
+		_, err = t.s.Db.MarkPostUnread(t.s.Context(), database.MarkPostUnreadParams{UserID: t.user.ID, PostID: post.ID})
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't mark post: %w", err)
+	}
+	post.IsRead = read
+	t.article = nil
+	return t.loadFeeds()
+}
+
+func (t *tui) toggleStar() error {
+	post := t.current()
+	if post == nil {
+		return nil
+	}
+	var err error
+	if post.IsStarred {
+		_, err = t.s.Db.UnstarPost(t.s.Context(), database.UnstarPostParams{UserID: t.user.ID, PostID: post.ID})
+	} else {
+		err = t.s.Db.StarPost(t.s.Context(), database.StarPostParams{UserID: t.user.ID, PostID: post.ID})
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't star post: %w", err)
+	}
+	post.IsStarred = !post.IsStarred
+	t.article = nil
+	return nil
+}
+
+func (t *tui) openLink() error {
+	post := t.current()
+	if post == nil {
+		return nil
+	}
+	if err := openURL(post.Url); err != nil {
+		return fmt.Errorf("couldn't open link: %w", err)
+	}
+	t.status = "Opened " + post.Url
+	return t.setRead(true)
+}
+
+// openURL opens url in the browser from $BROWSER or the system default.
+func openURL(url string) error {
+	name, args := "xdg-open", []string{url}
+	switch runtime.GOOS {
+	case "darwin":
+		name = "open"
+	case "windows":
+		name, args = "rundll32", []string{"url.dll,FileProtocolHandler", url}
+	}
+	if browser := os.Getenv("BROWSER"); browser != "" {
+		name, args = browser, []string{url}
+	}
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// layout returns the width of the feed pane and the heights of the post
+// list and the article pane.
+func (t *tui) layout() (feedWidth, postHeight, articleHeight int) {
+	body := t.rows - 2
+	feedWidth = min(30, t.cols/3)
+	postHeight = max(3, body*2/5)
+	articleHeight = max(body-postHeight-1, 1)
+	return feedWidth, postHeight, articleHeight
+}
+
+func (t *tui) articleHeight() int {
+	_, _, h := t.layout()
+	return h
+}
+
+func (t *tui) scrollArticle(delta int) {
+	_, _, h := t.layout()
+	t.articleTop = min(max(t.articleTop+delta, 0), max(len(t.article)-h, 0))
+}
+
+// resize reads the terminal size, which draw uses until the next resize.
+func (t *tui) resize() {
+	rows, cols := terminalSize()
+	if rows != t.rows || cols != t.cols {
+		t.rows, t.cols = rows, cols
+		t.article = nil
+		t.out.WriteString("\x1b[2J")
+	}
+}
+
+func (t *tui) draw() {
+	feedWidth, postHeight, articleHeight := t.layout()
+	rightWidth := t.cols - feedWidth - 1
+	if t.article == nil {
+		t.article = t.renderArticle(rightWidth - 2)
+		t.scrollArticle(0)
+	}
+
+	unread := int64(0)
+	for _, f := range t.feeds {
+		unread += f.Unread
+	}
+	header := fmt.Sprintf(" gator: %s, %d unread", t.user.Name, unread)
+	if t.unreadOnly {
+		header += ", showing unread posts only"
+	}
+	t.line(1, "\x1b[7m", fit(header, t.cols))
+
+	t.feedTop = scrollTo(t.feed, t.feedTop, t.rows-2)
+	t.postTop = scrollTo(t.post, t.postTop, postHeight)
+	for row := 0; row < t.rows-2; row++ {
+		var b strings.Builder
+		b.WriteString(t.feedLine(t.feedTop+row, feedWidth))
+		b.WriteString("│")
+		switch {
+		case row < postHeight:
+			b.WriteString(t.postLine(t.postTop+row, rightWidth))
+		case row == postHeight:
+			b.WriteString(strings.Repeat("─", rightWidth))
+		case row-postHeight-1 < articleHeight:
+			i := t.articleTop + row - postHeight - 1
+			text := ""
+			if i < len(t.article) {
+				text = " " + t.article[i]
+			}
+			b.WriteString(fit(text, rightWidth))
+		}
+		t.line(row+2, "", b.String())
+	}
+
+	status := t.status
+	if status == "" {
+		status = tuiHelp
+	}
+	t.line(t.rows, "\x1b[7m", fit(" "+status, t.cols))
+	t.out.Flush()
+}
+
+// line writes text at the start of a screen row in the given style.
+func (t *tui) line(row int, style, text string) {
+	fmt.Fprintf(t.out, "\x1b[%d;1H%s%s\x1b[0m", row, style, text)
+}
+
+// highlight styles the selected line, in reverse when its pane has focus.
+func (t *tui) highlight(text string, selected bool, pane tuiPane) string {
+	switch {
+	case !selected:
+		return text
+	case t.focus == pane:
+		return "\x1b[7m" + text + "\x1b[0m"
+	default:
+		return "\x1b[1m" + text + "\x1b[0m"
+	}
+}
+
+func (t *tui) feedLine(i, width int) string {
+	if i > len(t.feeds) {
+		return fit("", width)
+	}
+	name, unread := "All feeds", int64(0)
+	if i == 0 {
+		for _, f := range t.feeds {
+			unread += f.Unread
+		}
+	} else {
+		name, unread = stripControl(t.feeds[i-1].FeedName), t.feeds[i-1].Unread
+	}
+	count := ""
+	if unread > 0 {
+		count = " " + strconv.FormatInt(unread, 10)
+	}
+	text := fit(" "+name, width-utf8.RuneCountInString(count)) + count
+	return t.highlight(fit(text, width), i == t.feed, feedPane)
+}
+
+func (t *tui) postLine(i, width int) string {
+	if i >= len(t.posts) {
+		if i == 0 {
+			return fit(" No posts", width)
+		}
+		return fit("", width)
+	}
+	post := t.posts[i]
+	marks := []rune("   ")
+	if !post.IsRead {
+		marks[0] = '●'
+	}
+	if post.IsStarred {
+		marks[1] = '★'
+	}
+	text := string(marks) + post.PostedAt.Format("Jan 02") + "  " + stripControl(post.Title)
+	if t.feed == 0 {
+		text += "  (" + stripControl(post.FeedName) + ")"
+	}
+	return t.highlight(fit(text, width), i == t.post, postPane)
+}
+
+// renderArticle lays out the selected post for the article pane.
+func (t *tui) renderArticle(width int) []string {
+	post := t.current()
+	if post == nil {
+		return []string{}
+	}
+	width = max(width, 10)
+	var lines []string
+	for _, line := range wrap(stripControl(post.Title), width) {
+		lines = append(lines, "\x1b[1m"+line+"\x1b[0m")
+	}
+	status := post.PostedAt.Format("Mon Jan 2 2006") + " from " + stripControl(post.FeedName)
+	if !post.IsRead {
+		status += " (unread)"
+	}
+	if post.IsStarred {
+		status += " (starred)"
+	}
+	lines = append(lines, status, stripControl(post.Url), "")
+	for _, para := range strings.Split(htmlToText(post.Description.String), "\n") {
+		if para == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, wrap(para, width)...)
+	}
+	return lines
+}
+
+var (
+	htmlBreak     = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|ul|ol|blockquote|pre|tr)>`)
+	htmlListItem  = regexp.MustCompile(`(?i)<li[^>]*>`)
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+	lineSpaceRuns = regexp.MustCompile(`[ \t\r\f\v]+`)
+)
+
+// htmlToText turns a post description into plain text paragraphs.
+func htmlToText(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlListItem.ReplaceAllString(s, "\n• ")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(stripControl(lineSpaceRuns.ReplaceAllString(line, " ")))
+	}
+	s = strings.Join(lines, "\n")
+	return strings.TrimSpace(blankLines.ReplaceAllString(s, "\n\n"))
+}
+
+// wrap breaks text into lines of at most width runes at spaces.
+func wrap(text string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	n := 0
+	for _, word := range strings.Fields(text) {
+		w := visibleLen(word)
+		for w > width {
+			// words longer than a line are broken up
+			if n > 0 {
+				lines = append(lines, line.String())
+				line.Reset()
+				n = 0
+			}
+			r := []rune(word)
+			lines = append(lines, string(r[:width]))
+			word = string(r[width:])
+			w = visibleLen(word)
+		}
+		if n > 0 && n+1+w > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			n = 0
+		}
+		if n > 0 {
+			line.WriteByte(' ')
+			n++
+		}
+		line.WriteString(word)
+		n += w
+	}
+	if n > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// stripControl removes C0 and C1 control characters, such as the escape
+// starting a terminal sequence, from text taken from a feed, so a post
+// can't move the cursor or restyle the screen in the reader or a listing.
+// Tabs become spaces.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case isControl(r):
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r >= 0x7f && r <= 0x9f
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(s, ""))
+}
+
+// fit pads or cuts s to exactly width runes.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	n := visibleLen(s)
+	if n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	r := []rune(ansiEscape.ReplaceAllString(s, ""))
+	return string(r[:width-1]) + "…"
+}
+
+// scrollTo returns the first visible line of a list of the given height so
+// that the selected line stays in view.
+func scrollTo(selected, top, height int) int {
+	if selected < top {
+		return max(selected, 0)
+	}
+	if selected >= top+height {
+		return selected - height + 1
+	}
+	return top
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripControl(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain title", "plain title"},
+		{"\x1b[2Jcleared", "[2Jcleared"},
+		{"bell\x07 and\x00 nul", "bell and nul"},
+		{"c1\u009b31m csi", "c131m csi"},
+		{"tab\there", "tab here"},
+		{"ünïcödé ★", "ünïcödé ★"},
+	}
+	for _, tt := range tests {
+		if got := stripControl(tt.in); got != tt.want {
+			t.Errorf("stripControl(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHTMLToTextStripsEscapes(t *testing.T) {
+	got := htmlToText("<p>hello &#27;]0;pwned&#7;</p>world\x1b[31m")
+	if strings.ContainsAny(got, "\x1b\x07") {
+		t.Errorf("htmlToText() = %q, still contains control characters", got)
+	}
+}
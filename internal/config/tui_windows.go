@@ -0,0 +1,7 @@
+package config
+
+import "os"
+
+// notifyResize does nothing on Windows, which has no SIGWINCH. The reader
+// keeps the size it started with.
+func notifyResize(c chan<- os.Signal) {}
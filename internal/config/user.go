@@ -0,0 +1,257 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gator/internal/database"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// HandlerUser manages user accounts.
+//
+//	user info [name]
+//	user rename <name> <new_name>
+//	user delete <name> [--yes]
+//	user passwd [name]
+//	user promote <name>
+//	user demote <name>
+//
+// Users can rename and delete their own account and change their own
+// password, only admins can change other users.
+func HandlerUser(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("usage: user info [name] | rename <name> <new_name> | delete <name> [--yes] | passwd [name] | promote <name> | demote <name>")
+	}
+	args := cmd.Args[1:]
+	switch cmd.Args[0] {
+	case "info":
+		return userInfo(s, args, user)
+	case "rename":
+		return renameUser(s, args, user)
+	case "delete":
+		return deleteUser(s, args, user)
+	case "passwd":
+		return changePassword(s, args, user)
+	case "promote":
+		return setAdmin(s, args, user, true)
+	case "demote":
+		return setAdmin(s, args, user, false)
+	default:
+		return fmt.Errorf("unknown user command: %s", cmd.Args[0])
+	}
+}
+
+// lookupUserInfo fetches a user by name, defaulting to the current user.
+func lookupUserInfo(s *State, args []string, user database.User) (database.GetUserInfoRow, error) {
+	name := user.Name
+	if len(args) > 0 {
+		name = args[0]
+	}
+	info, err := s.Db.GetUserInfo(s.Context(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return info, fmt.Errorf("user with name %s does not exist", name)
+	}
+	if err != nil {
+		return info, fmt.Errorf("couldn't get user %s: %w", name, err)
+	}
+	return info, nil
+}
+
+func userInfo(s *State, args []string, user database.User) error {
+	info, err := lookupUserInfo(s, args, user)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Name:            %s\n", info.Name)
+	fmt.Printf("ID:              %s\n", info.ID)
+	fmt.Printf("Created:         %s\n", info.CreatedAt.Format("Mon Jan 2 2006 15:04"))
+	fmt.Printf("Admin:           %t\n", info.IsAdmin)
+	fmt.Printf("Password set:    %t\n", info.HasPassword)
+	fmt.Printf("Following:       %d feeds\n", info.Follows)
+	fmt.Printf("Feeds added:     %d\n", info.FeedsAdded)
+	fmt.Printf("Active sessions: %d\n", info.ActiveSessions)
+	return nil
+}
+
+func renameUser(s *State, args []string, user database.User) error {
+	if len(args) < 2 {
+		return errors.New("usage: user rename <name> <new_name>")
+	}
+	target, err := s.Db.GetUser(s.Context(), args[0])
+	if err != nil {
+		return fmt.Errorf("user with name %s does not exist", args[0])
+	}
+	if err := requireSelfOrAdmin(user, target.ID); err != nil {
+		return err
+	}
+	if _, err := s.Db.GetUser(s.Context(), args[1]); err == nil {
+		return fmt.Errorf("user with name '%s' already exists", args[1])
+	}
+	renamed, err := s.Db.RenameUser(s.Context(), database.RenameUserParams{ID: target.ID, Name: args[1]})
+	if err != nil {
+		return fmt.Errorf("couldn't rename user: %w", err)
+	}
+	fmt.Printf("User %s renamed to %s\n", target.Name, renamed.Name)
+	return nil
+}
+
+func deleteUser(s *State, args []string, user database.User) error {
+	fs := newFlagSet("user delete")
+	yes := fs.Bool("yes", false, "delete without asking for confirmation")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return errors.New("usage: user delete <name> [--yes]")
+	}
+	info, err := lookupUserInfo(s, args, user)
+	if err != nil {
+		return err
+	}
+	if err := requireSelfOrAdmin(user, info.ID); err != nil {
+		return err
+	}
+	if info.IsAdmin {
+		if err := checkNotLastAdmin(s); err != nil {
+			return err
+		}
+	}
+	feeds, err := s.Db.ListUserFeeds(s.Context(), uuid.NullUUID{UUID: info.ID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("couldn't list feeds added by %s: %w", info.Name, err)
+	}
+
+	// deleting a user cascades to everything that references them
+	fmt.Printf("Deleting %s also deletes:\n", info.Name)
+	fmt.Printf("  %d follows\n", info.Follows)
+	fmt.Printf("  %d active sessions\n", info.ActiveSessions)
+	fmt.Printf("  %d feeds they added, with their posts and everyone's follows of them\n", len(feeds))
+	for _, feed := range feeds {
+		warning := ""
+		if feed.OtherFollowers > 0 {
+			warning = fmt.Sprintf(", followed by %d other users", feed.OtherFollowers)
+		}
+		fmt.Printf("    %s (%s): %d posts%s\n", feed.Name, feed.Url, feed.Posts, warning)
+	}
+
+	if !*yes {
+		if !confirm(fmt.Sprintf("Delete user %s?", info.Name)) {
+			return errors.New("aborted, pass --yes to delete without confirmation")
+		}
+	}
+	if err := s.Db.DeleteUser(s.Context(), info.ID); err != nil {
+		return fmt.Errorf("couldn't delete user %s: %w", info.Name, err)
+	}
+	if user.ID == info.ID {
+		// the session was deleted with the user
+		s.Profile.SessionToken = ""
+	}
+	fmt.Printf("User %s deleted\n", info.Name)
+	return nil
+}
+
+// changePassword sets a user's password, defaulting to the current user.
+// Users changing their own enter the current one first. Admins can set
+// anyone's, which is how accounts created before passwords existed get
+// one, and the user's sessions are revoked.
+func changePassword(s *State, args []string, user database.User) error {
+	target := user
+	if len(args) > 0 && args[0] != user.Name {
+		if err := RequireAdmin(user); err != nil {
+			return err
+		}
+		var err error
+		if target, err = s.Db.GetUser(s.Context(), args[0]); err != nil {
+			return fmt.Errorf("user with name %s does not exist", args[0])
+		}
+	} else {
+		current, err := readPassword("Current password: ")
+		if err != nil {
+			return err
+		}
+		if !checkPassword(user.PasswordHash.String, current) {
+			return errors.New("incorrect password")
+		}
+	}
+	fmt.Fprintf(os.Stderr, "New password for %s\n", target.Name)
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	if err := setPassword(s, target, password); err != nil {
+		return err
+	}
+	if target.ID != user.ID {
+		if _, err := s.Db.RevokeUserSessions(s.Context(), target.ID); err != nil {
+			return fmt.Errorf("couldn't revoke sessions of %s: %w", target.Name, err)
+		}
+	}
+	fmt.Printf("Password for %s updated\n", target.Name)
+	return nil
+}
+
+// setAdmin grants or revokes admin rights. The first user registered in a
+// database is its first admin, a database created before admins existed
+// gets one from its operator, see the README.
+func setAdmin(s *State, args []string, user database.User, admin bool) error {
+	if len(args) < 1 {
+		return errors.New("usage: user promote|demote <name>")
+	}
+	if err := RequireAdmin(user); err != nil {
+		return err
+	}
+	target, err := s.Db.GetUser(s.Context(), args[0])
+	if err != nil {
+		return fmt.Errorf("user with name %s does not exist", args[0])
+	}
+	if target.IsAdmin == admin {
+		fmt.Printf("Nothing to do, %s is already %s\n", target.Name, roleName(admin))
+		return nil
+	}
+	if !admin {
+		if err := checkNotLastAdmin(s); err != nil {
+			return err
+		}
+	}
+	if err := s.Db.SetUserAdmin(s.Context(), database.SetUserAdminParams{ID: target.ID, IsAdmin: admin}); err != nil {
+		return fmt.Errorf("couldn't update user %s: %w", target.Name, err)
+	}
+	fmt.Printf("%s is now %s\n", target.Name, roleName(admin))
+	return nil
+}
+
+func roleName(admin bool) string {
+	if admin {
+		return "an admin"
+	}
+	return "a regular user"
+}
+
+// checkNotLastAdmin stops the last admin from being removed, which would
+// leave nobody able to run admin commands.
+func checkNotLastAdmin(s *State) error {
+	admins, err := s.Db.CountAdmins(s.Context())
+	if err != nil {
+		return fmt.Errorf("couldn't count admins: %w", err)
+	}
+	if admins <= 1 {
+		return errors.New("can't remove the last admin, promote another user first")
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	line, _ := stdinReader.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
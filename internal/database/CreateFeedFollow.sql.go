@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,10 +17,10 @@ const createFeedFollow = `-- name: CreateFeedFollow :many
 WITH inserted_feed_follow AS(
 
     INSERT INTO feed_follows (user_id, feed_id)
-    VALUES($1, $2) RETURNING id, created_at, updated_at, user_id, feed_id
+    VALUES($1, $2) RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title
 )
 SELECT 
-ff.id, ff.created_at, ff.updated_at, ff.user_id, ff.feed_id, f.name AS feed_name, u.name AS user_name
+ff.id, ff.created_at, ff.updated_at, ff.user_id, ff.feed_id, ff.folder_id, ff.title, f.name AS feed_name, u.name AS user_name
 FROM inserted_feed_follow ff
 INNER JOIN feeds f on ff.feed_id = f.id 
 INNER JOIN users u on ff.user_id = u.id
@@ -36,6 +37,8 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
 	FeedName  string
 	UserName  string
 }
@@ -55,6 +58,8 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
 			&i.FeedName,
 			&i.UserName,
 		); err != nil {
@@ -16,7 +16,7 @@ INSERT INTO feeds (name, url, user_id)
 VALUES(
     $1, $2, $3 
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, claimed_until, last_attempt_at
 `
 
 type CreateFeedParams struct {
@@ -36,6 +36,9 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.LastAttemptAt,
 	)
 	return i, err
 }
@@ -7,27 +7,43 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 
-SELECT ff.id, ff.created_at, ff.updated_at, ff.user_id, ff.feed_id, f.name AS feed_name, u.name AS user_name FROM feed_follows ff
+SELECT ff.id, ff.created_at, ff.updated_at, ff.user_id, ff.feed_id, ff.folder_id, ff.title, COALESCE(ff.title, f.name) AS feed_name, f.url AS feed_url, u.name AS user_name, fo.name AS folder_name,
+(SELECT count(*) FROM posts p
+    LEFT JOIN post_states ps ON ps.post_id = p.id AND ps.user_id = ff.user_id
+    WHERE p.feed_id = ff.feed_id AND ps.read_at IS NULL
+    AND NOT post_is_muted(ff.user_id, p)) AS unread,
+ARRAY(SELECT t.tag FROM follow_tags t WHERE t.follow_id = ff.id ORDER BY t.tag)::text[] AS tags
+FROM feed_follows ff
 INNER JOIN feeds f ON ff.feed_id=f.id
 INNER JOIN users u ON ff.user_id=u.id
+LEFT JOIN folders fo ON ff.folder_id=fo.id
 WHERE ff.user_id = $1
+ORDER BY lower(COALESCE(ff.title, f.name))
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	FolderID   uuid.NullUUID
+	Title      sql.NullString
+	FeedName   string
+	FeedUrl    string
+	UserName   string
+	FolderName sql.NullString
+	Unread     int64
+	Tags       []string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -45,8 +61,14 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
 			&i.FeedName,
+			&i.FeedUrl,
 			&i.UserName,
+			&i.FolderName,
+			&i.Unread,
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
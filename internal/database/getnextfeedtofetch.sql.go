@@ -10,7 +10,7 @@ import (
 )
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, claimed_until, last_attempt_at FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1
 `
 
 func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
@@ -24,6 +24,9 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.LastAttemptAt,
 	)
 	return i, err
 }
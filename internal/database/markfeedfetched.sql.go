@@ -18,7 +18,7 @@ UPDATE feeds
 SET last_fetched_at = now(),
 updated_at = now()
 WHERE feeds.id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, claimed_until, last_attempt_at
 `
 
 func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -32,6 +32,9 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, erro
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.LastAttemptAt,
 	)
 	return i, err
 }
@@ -11,6 +11,44 @@ import (
 	"github.com/google/uuid"
 )
 
+type AggregatorHeartbeat struct {
+	Instance        string
+	StartedAt       time.Time
+	LastSeenAt      time.Time
+	LastSuccessAt   sql.NullTime
+	StoppedAt       sql.NullTime
+	IntervalSeconds float64
+	FeedsFetched    int32
+	FeedsFailed     int32
+	Once            bool
+}
+
+type Alert struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	RuleID    uuid.UUID
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	SeenAt    sql.NullTime
+}
+
+type AlertRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Pattern   string
+	IsRegex   bool
+}
+
+type DefaultRetention struct {
+	ID            bool
+	MaxAgeSeconds float64
+	MaxPosts      int32
+	KeepUnread    bool
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
@@ -19,6 +57,9 @@ type Feed struct {
 	Url           string
 	UserID        uuid.NullUUID
 	LastFetchedAt sql.NullTime
+	ClaimedBy     sql.NullString
+	ClaimedUntil  sql.NullTime
+	LastAttemptAt sql.NullTime
 }
 
 type FeedFollow struct {
@@ -27,6 +68,38 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+}
+
+type FeedRetention struct {
+	FeedID        uuid.UUID
+	MaxAgeSeconds sql.NullFloat64
+	MaxPosts      sql.NullInt32
+	KeepUnread    sql.NullBool
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+}
+
+type FollowTag struct {
+	FollowID  uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type Mute struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	Pattern   string
+	IsRegex   bool
 }
 
 type Post struct {
@@ -37,12 +110,43 @@ type Post struct {
 	Url         string
 	Description sql.NullString
 	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	FeedID      uuid.NullUUID
+	Content     sql.NullString
+	Search      interface{}
+	Author      sql.NullString
+	FeedUrl     sql.NullString
 }
 
-type User struct {
+type PostRevision struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	PostID      uuid.UUID
+	Title       string
+	Description sql.NullString
+	Content     sql.NullString
+}
+
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+}
+
+type Session struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+	TokenHash string
+	UserID    uuid.UUID
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	IsAdmin      bool
 }
@@ -7,13 +7,310 @@ package database
 
 import (
 	"context"
+
+	"github.com/google/uuid"
+)
+
+const resetAlertRules = `-- name: ResetAlertRules :one
+WITH deleted AS (
+    DELETE FROM alert_rules
+    WHERE ($1::bool
+        AND ($2::uuid IS NULL OR alert_rules.user_id = $2::uuid))
+    OR ($3::bool AND feed_id IN (
+        SELECT feeds.id FROM feeds
+        WHERE $2::uuid IS NULL OR feeds.user_id = $2::uuid
+    ))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+type ResetAlertRulesParams struct {
+	ByUser  bool
+	UserID  uuid.NullUUID
+	OfFeeds bool
+}
+
+// Deletes the user's own alert rules when by_user is set, and every rule
+// scoped to the feeds they added when of_feeds is set.
+func (q *Queries) ResetAlertRules(ctx context.Context, arg ResetAlertRulesParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetAlertRules, arg.ByUser, arg.UserID, arg.OfFeeds)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetAlerts = `-- name: ResetAlerts :one
+WITH deleted AS (
+    DELETE FROM alerts
+    WHERE ($1::bool AND post_id IN (
+        SELECT posts.id FROM posts
+        LEFT JOIN feeds ON feeds.id = posts.feed_id
+        WHERE $2::uuid IS NULL OR feeds.user_id = $2::uuid
+    ))
+    OR ($3::bool
+        AND ($2::uuid IS NULL OR alerts.user_id = $2::uuid))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+type ResetAlertsParams struct {
+	OfFeeds bool
+	UserID  uuid.NullUUID
+	ByUser  bool
+}
+
+// Deletes the alerts raised by posts in the feeds the user added when
+// of_feeds is set, and the user's own alerts when by_user is set.
+func (q *Queries) ResetAlerts(ctx context.Context, arg ResetAlertsParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetAlerts, arg.OfFeeds, arg.UserID, arg.ByUser)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetFeedFollows = `-- name: ResetFeedFollows :one
+WITH deleted AS (
+    DELETE FROM feed_follows
+    WHERE ($1::bool
+        AND ($2::uuid IS NULL OR feed_follows.user_id = $2::uuid))
+    OR ($3::bool AND feed_id IN (
+        SELECT feeds.id FROM feeds
+        WHERE $2::uuid IS NULL OR feeds.user_id = $2::uuid
+    ))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+type ResetFeedFollowsParams struct {
+	ByUser  bool
+	UserID  uuid.NullUUID
+	OfFeeds bool
+}
+
+// Deletes the user's own follows when by_user is set, and every follow of
+// the feeds they added when of_feeds is set.
+func (q *Queries) ResetFeedFollows(ctx context.Context, arg ResetFeedFollowsParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetFeedFollows, arg.ByUser, arg.UserID, arg.OfFeeds)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetFeedRetention = `-- name: ResetFeedRetention :one
+WITH deleted AS (
+    DELETE FROM feed_retention
+    WHERE feed_id IN (
+        SELECT feeds.id FROM feeds
+        WHERE $1::uuid IS NULL OR feeds.user_id = $1::uuid
+    )
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+func (q *Queries) ResetFeedRetention(ctx context.Context, userID uuid.NullUUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetFeedRetention, userID)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetFeeds = `-- name: ResetFeeds :one
+WITH deleted AS (
+    DELETE FROM feeds
+    WHERE $1::uuid IS NULL OR feeds.user_id = $1::uuid
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+func (q *Queries) ResetFeeds(ctx context.Context, userID uuid.NullUUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetFeeds, userID)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetFolders = `-- name: ResetFolders :one
+WITH deleted AS (
+    DELETE FROM folders
+    WHERE $1::uuid IS NULL OR folders.user_id = $1::uuid
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+func (q *Queries) ResetFolders(ctx context.Context, userID uuid.NullUUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetFolders, userID)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetFollowTags = `-- name: ResetFollowTags :one
+WITH deleted AS (
+    DELETE FROM follow_tags
+    WHERE follow_id IN (
+        SELECT feed_follows.id FROM feed_follows
+        WHERE ($1::bool
+            AND ($2::uuid IS NULL OR feed_follows.user_id = $2::uuid))
+        OR ($3::bool AND feed_id IN (
+            SELECT feeds.id FROM feeds
+            WHERE $2::uuid IS NULL OR feeds.user_id = $2::uuid
+        ))
+    )
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+type ResetFollowTagsParams struct {
+	ByUser  bool
+	UserID  uuid.NullUUID
+	OfFeeds bool
+}
+
+// Deletes the tags of the follows ResetFeedFollows deletes with the same
+// arguments.
+func (q *Queries) ResetFollowTags(ctx context.Context, arg ResetFollowTagsParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetFollowTags, arg.ByUser, arg.UserID, arg.OfFeeds)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetMutes = `-- name: ResetMutes :one
+WITH deleted AS (
+    DELETE FROM mutes
+    WHERE ($1::bool
+        AND ($2::uuid IS NULL OR mutes.user_id = $2::uuid))
+    OR ($3::bool AND feed_id IN (
+        SELECT feeds.id FROM feeds
+        WHERE $2::uuid IS NULL OR feeds.user_id = $2::uuid
+    ))
+    RETURNING *
 )
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+type ResetMutesParams struct {
+	ByUser  bool
+	UserID  uuid.NullUUID
+	OfFeeds bool
+}
+
+// Deletes the user's own mute rules when by_user is set, and every rule
+// scoped to the feeds they added when of_feeds is set.
+func (q *Queries) ResetMutes(ctx context.Context, arg ResetMutesParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetMutes, arg.ByUser, arg.UserID, arg.OfFeeds)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetPostRevisions = `-- name: ResetPostRevisions :one
+WITH deleted AS (
+    DELETE FROM post_revisions
+    WHERE post_id IN (
+        SELECT posts.id FROM posts
+        LEFT JOIN feeds ON feeds.id = posts.feed_id
+        WHERE $1::uuid IS NULL OR feeds.user_id = $1::uuid
+    )
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+// The Reset queries delete rows belonging to user_id, or every row when it
+// is NULL, and return the deleted rows as a JSON array. Posts, feeds and
+// their dependents belong to the user who added the feed; starred posts of
+// deleted feeds belong to nobody and only go in a full reset.
+func (q *Queries) ResetPostRevisions(ctx context.Context, userID uuid.NullUUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetPostRevisions, userID)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
 
-const resetTable = `-- name: ResetTable :exec
-DELETE FROM users *
+const resetPostStates = `-- name: ResetPostStates :one
+WITH deleted AS (
+    DELETE FROM post_states
+    WHERE ($1::bool AND post_id IN (
+        SELECT posts.id FROM posts
+        LEFT JOIN feeds ON feeds.id = posts.feed_id
+        WHERE $2::uuid IS NULL OR feeds.user_id = $2::uuid
+    ))
+    OR ($3::bool
+        AND ($2::uuid IS NULL OR post_states.user_id = $2::uuid))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+type ResetPostStatesParams struct {
+	OfFeeds bool
+	UserID  uuid.NullUUID
+	ByUser  bool
+}
+
+// Deletes the read state of posts in the feeds the user added when of_feeds
+// is set, and the user's own read state when by_user is set.
+func (q *Queries) ResetPostStates(ctx context.Context, arg ResetPostStatesParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetPostStates, arg.OfFeeds, arg.UserID, arg.ByUser)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetPosts = `-- name: ResetPosts :one
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE $1::uuid IS NULL OR feed_id IN (
+        SELECT feeds.id FROM feeds WHERE feeds.user_id = $1::uuid
+    )
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+func (q *Queries) ResetPosts(ctx context.Context, userID uuid.NullUUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetPosts, userID)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetSessions = `-- name: ResetSessions :one
+WITH deleted AS (
+    DELETE FROM sessions
+    WHERE $1::uuid IS NULL OR sessions.user_id = $1::uuid
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted
+`
+
+func (q *Queries) ResetSessions(ctx context.Context, userID uuid.NullUUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetSessions, userID)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
+}
+
+const resetUsers = `-- name: ResetUsers :one
+WITH deleted AS (
+    DELETE FROM users
+    WHERE $1::uuid IS NULL OR users.id = $1::uuid
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text FROM deleted
 `
 
-func (q *Queries) ResetTable(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, resetTable)
-	return err
+func (q *Queries) ResetUsers(ctx context.Context, userID uuid.NullUUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, resetUsers, userID)
+	var deleted_rows string
+	err := row.Scan(&deleted_rows)
+	return deleted_rows, err
 }
@@ -7,42 +7,217 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countAdmins = `-- name: CountAdmins :one
+SELECT count(*) FROM users WHERE is_admin
+`
+
+func (q *Queries) CountAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, is_admin)
 VALUES(
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    NOT EXISTS (SELECT 1 FROM users)
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, password_hash, is_admin
 `
 
 type CreateUserParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
 }
 
+// The first user of a new database is made an admin.
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
 	row := q.db.QueryRowContext(ctx, createUser,
 		arg.ID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
 		arg.Name,
+		arg.PasswordHash,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.PasswordHash,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const getUserInfo = `-- name: GetUserInfo :one
+SELECT users.id, users.created_at, users.name,
+users.password_hash IS NOT NULL AS has_password,
+users.is_admin,
+(SELECT count(*) FROM feed_follows WHERE feed_follows.user_id = users.id) AS follows,
+(SELECT count(*) FROM feeds WHERE feeds.user_id = users.id) AS feeds_added,
+(SELECT count(*) FROM sessions WHERE sessions.user_id = users.id
+    AND sessions.revoked_at IS NULL AND sessions.expires_at > now()) AS active_sessions
+FROM users
+WHERE users.name = $1
+`
+
+type GetUserInfoRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	Name           string
+	HasPassword    bool
+	IsAdmin        bool
+	Follows        int64
+	FeedsAdded     int64
+	ActiveSessions int64
+}
+
+func (q *Queries) GetUserInfo(ctx context.Context, name string) (GetUserInfoRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserInfo, name)
+	var i GetUserInfoRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Name,
+		&i.HasPassword,
+		&i.IsAdmin,
+		&i.Follows,
+		&i.FeedsAdded,
+		&i.ActiveSessions,
 	)
+	return i, err
+}
+
+const listUserFeeds = `-- name: ListUserFeeds :many
+SELECT feeds.name, feeds.url,
+(SELECT count(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> feeds.user_id) AS other_followers,
+(SELECT count(*) FROM posts WHERE posts.feed_id = feeds.id) AS posts
+FROM feeds
+WHERE feeds.user_id = $1
+ORDER BY feeds.name
+`
+
+type ListUserFeedsRow struct {
+	Name           string
+	Url            string
+	OtherFollowers int64
+	Posts          int64
+}
+
+// Lists the feeds a user added, which are deleted along with them, and how
+// many other users follow each.
+func (q *Queries) ListUserFeeds(ctx context.Context, userID uuid.NullUUID) ([]ListUserFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserFeeds, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserFeedsRow
+	for rows.Next() {
+		var i ListUserFeedsRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.OtherFollowers,
+			&i.Posts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameUser = `-- name: RenameUser :one
+UPDATE users
+SET name = $2,
+updated_at = now()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, password_hash, is_admin
+`
+
+type RenameUserParams struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg RenameUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, renameUser, arg.ID, arg.Name)
 	var i User
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.PasswordHash,
+		&i.IsAdmin,
 	)
 	return i, err
 }
+
+const setUserPassword = `-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2,
+updated_at = now()
+WHERE id = $1
+`
+
+type SetUserPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash sql.NullString
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const setUserAdmin = `-- name: SetUserAdmin :exec
+UPDATE users
+SET is_admin = $2,
+updated_at = now()
+WHERE id = $1
+`
+
+type SetUserAdminParams struct {
+	ID      uuid.UUID
+	IsAdmin bool
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) error {
+	_, err := q.db.ExecContext(ctx, setUserAdmin, arg.ID, arg.IsAdmin)
+	return err
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(r *Registry) string {
+	var b strings.Builder
+	r.Write(&b)
+	return b.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("gator_things_total", "Things done.")
+	c.Inc()
+	c.Add(2.5)
+	want := `# HELP gator_things_total Things done.
+# TYPE gator_things_total counter
+gator_things_total 3.5
+`
+	if got := render(r); got != want {
+		t.Errorf("counter output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("gator_fetches_total", "Fetches by result.", "result")
+	c.Inc("success")
+	c.Inc("error")
+	c.Add("success", 2)
+	want := `# HELP gator_fetches_total Fetches by result.
+# TYPE gator_fetches_total counter
+gator_fetches_total{result="error"} 1
+gator_fetches_total{result="success"} 3
+`
+	if got := render(r); got != want {
+		t.Errorf("counter vec output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterVecEmpty(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("gator_errors_total", "Errors.", "op")
+	want := `# HELP gator_errors_total Errors.
+# TYPE gator_errors_total counter
+`
+	if got := render(r); got != want {
+		t.Errorf("empty counter vec output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("gator_feeds_due", "Feeds due.")
+	g.Set(7)
+	g.Set(4)
+	want := `# HELP gator_feeds_due Feeds due.
+# TYPE gator_feeds_due gauge
+gator_feeds_due 4
+`
+	if got := render(r); got != want {
+		t.Errorf("gauge output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	// buckets are sorted whatever order they are given in
+	h := r.NewHistogram("gator_fetch_seconds", "Fetch duration.", []float64{1, 0.5, 5})
+	for _, v := range []float64{0.2, 0.5, 0.7, 3, 60} {
+		h.Observe(v)
+	}
+	want := `# HELP gator_fetch_seconds Fetch duration.
+# TYPE gator_fetch_seconds histogram
+gator_fetch_seconds_bucket{le="0.5"} 2
+gator_fetch_seconds_bucket{le="1"} 3
+gator_fetch_seconds_bucket{le="5"} 4
+gator_fetch_seconds_bucket{le="+Inf"} 5
+gator_fetch_seconds_sum 64.4
+gator_fetch_seconds_count 5
+`
+	if got := render(r); got != want {
+		t.Errorf("histogram output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("gator_odd_total", "Help with a \\ and a\nnewline.", "value")
+	c.Inc("say \"hi\"")
+	c.Inc(`C:\feeds`)
+	c.Inc("two\nlines")
+	want := `# HELP gator_odd_total Help with a \\ and a\nnewline.
+# TYPE gator_odd_total counter
+gator_odd_total{value="C:\\feeds"} 1
+gator_odd_total{value="say \"hi\""} 1
+gator_odd_total{value="two\nlines"} 1
+`
+	if got := render(r); got != want {
+		t.Errorf("escaped output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistryOrderAndHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("b", "Second.").Set(1)
+	r.NewCounter("a", "First.").Inc()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	want := `# HELP b Second.
+# TYPE b gauge
+b 1
+# HELP a First.
+# TYPE a counter
+a 1
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("registry output:\n%s\nwant:\n%s", got, want)
+	}
+}
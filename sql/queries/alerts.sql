@@ -0,0 +1,63 @@
+-- name: CreateAlertRule :one
+INSERT INTO alert_rules (user_id, feed_id, field, pattern, is_regex)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: CreateAlerts :many
+-- Records an alert for every alert rule matching one of the feed's posts
+-- with the given URLs and returns the new alerts. Rules apply to posts of
+-- every feed, not only those their user follows, and each rule raises at
+-- most one alert per post.
+WITH created AS (
+    INSERT INTO alerts (rule_id, user_id, post_id)
+    SELECT alert_rules.id, alert_rules.user_id, posts.id
+    FROM posts
+    JOIN alert_rules ON alert_rules.feed_id IS NULL OR alert_rules.feed_id = posts.feed_id
+    WHERE posts.feed_id = sqlc.arg(feed_id)::uuid
+    AND posts.url = ANY(sqlc.arg(urls)::text[])
+    AND rule_matches(alert_rules.field, alert_rules.pattern, alert_rules.is_regex,
+        posts.title, posts.description, posts.author, posts.url)
+    ON CONFLICT (rule_id, post_id) DO NOTHING
+    RETURNING *
+)
+SELECT created.id, created.created_at, created.user_id, users.name AS user_name,
+created.rule_id, alert_rules.field, alert_rules.pattern, alert_rules.is_regex,
+created.post_id, posts.title AS post_title, posts.url AS post_url, feeds.name AS feed_name
+FROM created
+JOIN users ON users.id = created.user_id
+JOIN alert_rules ON alert_rules.id = created.rule_id
+JOIN posts ON posts.id = created.post_id
+JOIN feeds ON feeds.id = posts.feed_id
+ORDER BY users.name, posts.title;
+
+-- name: DeleteAlertRule :execrows
+DELETE FROM alert_rules WHERE id = $1 AND user_id = $2;
+
+-- name: ListAlertRules :many
+-- Lists the user's alert rules with how many alerts each one has raised.
+SELECT alert_rules.*, feeds.url AS feed_url,
+(SELECT count(*) FROM alerts WHERE alerts.rule_id = alert_rules.id) AS raised
+FROM alert_rules
+LEFT JOIN feeds ON feeds.id = alert_rules.feed_id
+WHERE alert_rules.user_id = $1
+ORDER BY alert_rules.created_at;
+
+-- name: ListAlerts :many
+-- Lists the user's alerts, newest first. Alerts already seen are left out
+-- unless include_seen is set. Posts of deleted feeds have an empty
+-- feed_name.
+SELECT alerts.id, alerts.created_at, alerts.seen_at, alerts.rule_id,
+alert_rules.field, alert_rules.pattern, alert_rules.is_regex,
+alerts.post_id, posts.title AS post_title, posts.url AS post_url, COALESCE(feeds.name, '') AS feed_name
+FROM alerts
+JOIN alert_rules ON alert_rules.id = alerts.rule_id
+JOIN posts ON posts.id = alerts.post_id
+LEFT JOIN feeds ON feeds.id = posts.feed_id
+WHERE alerts.user_id = sqlc.arg(user_id)
+AND (sqlc.arg(include_seen)::bool OR alerts.seen_at IS NULL)
+ORDER BY alerts.created_at DESC, alerts.id
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkAlertsSeen :execrows
+UPDATE alerts SET seen_at = now()
+WHERE user_id = $1 AND seen_at IS NULL;
@@ -0,0 +1,57 @@
+-- name: BrowsePosts :many
+-- Lists posts in the feeds the user follows, newest first. Pass the
+-- posted_at and id of the last post of a page as after_posted_at and
+-- after_id to get the next one. Posts hidden by the user's mute rules are
+-- left out unless show_muted is set.
+SELECT posts.id, posts.title, posts.url, posts.description, posts.published_at,
+COALESCE(feed_follows.title, feeds.name) AS feed_name,
+COALESCE(posts.published_at, posts.created_at)::timestamp AS posted_at,
+post_states.read_at IS NOT NULL AS is_read,
+post_states.starred_at IS NOT NULL AS is_starred,
+post_is_muted(feed_follows.user_id, posts) AS is_muted
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON feeds.id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND browse_filter(feed_follows, posts, post_states, sqlc.arg(unread_only)::bool,
+    sqlc.narg(feed_url)::text, sqlc.narg(folder)::text, sqlc.narg(tag)::text,
+    sqlc.narg(since)::timestamp, sqlc.narg(until)::timestamp)
+AND (sqlc.arg(show_muted)::bool OR NOT post_is_muted(feed_follows.user_id, posts))
+AND (sqlc.narg(after_posted_at)::timestamp IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) < (sqlc.narg(after_posted_at)::timestamp, sqlc.arg(after_id)::uuid))
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: BrowsePostsOldestFirst :many
+-- Like BrowsePosts, oldest first.
+SELECT posts.id, posts.title, posts.url, posts.description, posts.published_at,
+COALESCE(feed_follows.title, feeds.name) AS feed_name,
+COALESCE(posts.published_at, posts.created_at)::timestamp AS posted_at,
+post_states.read_at IS NOT NULL AS is_read,
+post_states.starred_at IS NOT NULL AS is_starred,
+post_is_muted(feed_follows.user_id, posts) AS is_muted
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON feeds.id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND browse_filter(feed_follows, posts, post_states, sqlc.arg(unread_only)::bool,
+    sqlc.narg(feed_url)::text, sqlc.narg(folder)::text, sqlc.narg(tag)::text,
+    sqlc.narg(since)::timestamp, sqlc.narg(until)::timestamp)
+AND (sqlc.arg(show_muted)::bool OR NOT post_is_muted(feed_follows.user_id, posts))
+AND (sqlc.narg(after_posted_at)::timestamp IS NULL
+    OR (COALESCE(posts.published_at, posts.created_at), posts.id) > (sqlc.narg(after_posted_at)::timestamp, sqlc.arg(after_id)::uuid))
+ORDER BY COALESCE(posts.published_at, posts.created_at) ASC, posts.id ASC
+LIMIT sqlc.arg(page_size);
+
+-- name: CountMutedPosts :one
+-- Counts the posts BrowsePosts leaves out because of the user's mute rules.
+SELECT count(*) FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND browse_filter(feed_follows, posts, post_states, sqlc.arg(unread_only)::bool,
+    sqlc.narg(feed_url)::text, sqlc.narg(folder)::text, sqlc.narg(tag)::text,
+    sqlc.narg(since)::timestamp, sqlc.narg(until)::timestamp)
+AND post_is_muted(feed_follows.user_id, posts);
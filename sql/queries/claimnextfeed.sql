@@ -0,0 +1,14 @@
+-- name: ClaimNextFeed :one
+UPDATE feeds
+SET claimed_by = sqlc.arg(claimed_by),
+claimed_until = now() + make_interval(secs => sqlc.arg(lease_seconds)::float8),
+last_attempt_at = now()
+WHERE feeds.id = (
+    SELECT f.id FROM feeds f
+    WHERE (f.claimed_until IS NULL OR f.claimed_until < now())
+    AND (f.last_attempt_at IS NULL OR f.last_attempt_at < now() - make_interval(secs => sqlc.arg(due_after_seconds)::float8))
+    ORDER BY f.last_attempt_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
@@ -0,0 +1,4 @@
+-- name: CountDueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE (claimed_until IS NULL OR claimed_until < now())
+AND (last_attempt_at IS NULL OR last_attempt_at < now() - make_interval(secs => sqlc.arg(due_after_seconds)::float8));
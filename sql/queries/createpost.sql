@@ -1,6 +1,6 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content)
 VALUES(
-    $1, $2, $3, $4, $5, $6,$7, $8
+    $1, $2, $3, $4, $5, $6,$7, $8, $9
 )
-RETURNING *;
\ No newline at end of file
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, author;
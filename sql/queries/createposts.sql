@@ -0,0 +1,31 @@
+-- name: CreatePosts :many
+-- Inserts a batch of posts for one feed, skipping URLs that are already
+-- stored. Starred posts kept after their feed was deleted are adopted again
+-- by a feed with the URL they were collected from. Returns the URLs that
+-- were inserted or adopted.
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, feed_url, content, author)
+SELECT
+    i.id,
+    sqlc.arg(created_at),
+    sqlc.arg(created_at),
+    i.title,
+    i.url,
+    i.description,
+    NULLIF(i.published_at, '')::timestamp,
+    sqlc.arg(feed_id)::uuid,
+    (SELECT feeds.url FROM feeds WHERE feeds.id = sqlc.arg(feed_id)::uuid),
+    NULLIF(i.content, ''),
+    NULLIF(i.author, '')
+FROM unnest(
+    sqlc.arg(ids)::uuid[],
+    sqlc.arg(titles)::text[],
+    sqlc.arg(urls)::text[],
+    sqlc.arg(descriptions)::text[],
+    sqlc.arg(published_ats)::text[],
+    sqlc.arg(contents)::text[],
+    sqlc.arg(authors)::text[]
+) AS i(id, title, url, description, published_at, content, author)
+ON CONFLICT (url) DO UPDATE SET
+feed_id = EXCLUDED.feed_id
+WHERE posts.feed_id IS NULL AND posts.feed_url = EXCLUDED.feed_url
+RETURNING url;
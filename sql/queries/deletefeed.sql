@@ -0,0 +1,11 @@
+-- name: GetFeedUsage :one
+SELECT
+(SELECT count(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS followers,
+(SELECT count(*) FROM posts WHERE posts.feed_id = $1) AS posts,
+(SELECT count(*) FROM posts WHERE posts.feed_id = $1 AND EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+)) AS starred;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
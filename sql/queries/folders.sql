@@ -0,0 +1,24 @@
+-- name: CreateFolder :one
+INSERT INTO folders (user_id, name)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders WHERE user_id = $1 AND name = $2;
+
+-- name: GetFolder :one
+SELECT * FROM folders WHERE user_id = $1 AND name = $2;
+
+-- name: ListFolders :many
+SELECT * FROM folders WHERE user_id = $1 ORDER BY name;
+
+-- name: RenameFolder :execrows
+UPDATE folders SET name = sqlc.arg(new_name)
+WHERE user_id = sqlc.arg(user_id) AND name = sqlc.arg(name);
+
+-- name: SetFollowFolder :execrows
+-- Moves the user's follow of a feed into a folder, or out of any folder
+-- when folder_id is null.
+UPDATE feed_follows SET folder_id = sqlc.narg(folder_id), updated_at = now()
+WHERE user_id = sqlc.arg(user_id)
+AND feed_id = (SELECT id FROM feeds WHERE url = sqlc.arg(feed_url));
@@ -1,6 +1,14 @@
 -- name: GetFeedFollowsForUser :many
 
-SELECT ff.*, f.name AS feed_name, u.name AS user_name FROM feed_follows ff
+SELECT ff.*, COALESCE(ff.title, f.name) AS feed_name, f.url AS feed_url, u.name AS user_name, fo.name AS folder_name,
+(SELECT count(*) FROM posts p
+    LEFT JOIN post_states ps ON ps.post_id = p.id AND ps.user_id = ff.user_id
+    WHERE p.feed_id = ff.feed_id AND ps.read_at IS NULL
+    AND NOT post_is_muted(ff.user_id, p)) AS unread,
+ARRAY(SELECT t.tag FROM follow_tags t WHERE t.follow_id = ff.id ORDER BY t.tag)::text[] AS tags
+FROM feed_follows ff
 INNER JOIN feeds f ON ff.feed_id=f.id
 INNER JOIN users u ON ff.user_id=u.id
-WHERE ff.user_id = $1;
+LEFT JOIN folders fo ON ff.folder_id=fo.id
+WHERE ff.user_id = $1
+ORDER BY lower(COALESCE(ff.title, f.name));
@@ -1,7 +0,0 @@
--- name: GetPostsForUser :many
-SELECT posts.*, feeds.name AS feed_name FROM posts
-JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
-JOIN feeds ON posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC 
-LIMIT $2;
\ No newline at end of file
@@ -0,0 +1,34 @@
+-- name: RecordHeartbeat :exec
+INSERT INTO aggregator_heartbeats (instance, interval_seconds, once, last_success_at, feeds_fetched, feeds_failed)
+VALUES(
+    sqlc.arg(instance),
+    sqlc.arg(interval_seconds),
+    sqlc.arg(once),
+    CASE WHEN sqlc.arg(succeeded)::bool THEN now() END,
+    sqlc.arg(feeds_fetched),
+    sqlc.arg(feeds_failed)
+)
+ON CONFLICT (instance) DO UPDATE SET
+last_seen_at = now(),
+interval_seconds = EXCLUDED.interval_seconds,
+once = EXCLUDED.once,
+last_success_at = COALESCE(EXCLUDED.last_success_at, aggregator_heartbeats.last_success_at),
+feeds_fetched = EXCLUDED.feeds_fetched,
+feeds_failed = EXCLUDED.feeds_failed,
+stopped_at = NULL;
+
+-- name: StopHeartbeat :exec
+UPDATE aggregator_heartbeats
+SET stopped_at = now(),
+last_seen_at = now()
+WHERE instance = $1;
+
+-- name: DeleteStaleHeartbeats :execrows
+-- Forgets instances that have not reported for max_age_seconds, whether
+-- they stopped or died.
+DELETE FROM aggregator_heartbeats
+WHERE last_seen_at < now() - make_interval(secs => sqlc.arg(max_age_seconds)::float8);
+
+-- name: ListHeartbeats :many
+SELECT *, now()::timestamp AS db_now FROM aggregator_heartbeats
+ORDER BY last_seen_at DESC;
@@ -0,0 +1,25 @@
+-- name: CheckRegex :exec
+-- Fails if pattern is not a valid regular expression for rule_matches.
+SELECT '' ~* sqlc.arg(pattern)::text;
+
+-- name: CreateMute :one
+INSERT INTO mutes (user_id, feed_id, field, pattern, is_regex)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: DeleteMute :execrows
+DELETE FROM mutes WHERE id = $1 AND user_id = $2;
+
+-- name: ListMutes :many
+-- Lists the user's mute rules with how many posts in the feeds they follow
+-- each one hides.
+SELECT mutes.*, feeds.url AS feed_url,
+(SELECT count(*) FROM posts
+    JOIN feed_follows ff ON ff.feed_id = posts.feed_id AND ff.user_id = mutes.user_id
+    WHERE (mutes.feed_id IS NULL OR posts.feed_id = mutes.feed_id)
+    AND rule_matches(mutes.field, mutes.pattern, mutes.is_regex,
+        posts.title, posts.description, posts.author, posts.url)) AS hidden
+FROM mutes
+LEFT JOIN feeds ON feeds.id = mutes.feed_id
+WHERE mutes.user_id = $1
+ORDER BY mutes.created_at;
@@ -0,0 +1,10 @@
+-- name: GetPost :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.author,
+COALESCE(feeds.name, '') AS feed_name FROM posts
+LEFT JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.id = $1;
+
+-- name: ListPostRevisions :many
+SELECT * FROM post_revisions
+WHERE post_id = $1
+ORDER BY created_at ASC;
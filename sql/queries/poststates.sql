@@ -0,0 +1,45 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES ($1, $2, now())
+ON CONFLICT (user_id, post_id) DO UPDATE SET
+read_at = COALESCE(post_states.read_at, EXCLUDED.read_at);
+
+-- name: MarkPostUnread :execrows
+UPDATE post_states SET read_at = NULL
+WHERE user_id = $1 AND post_id = $2 AND read_at IS NOT NULL;
+
+-- name: MarkPostsRead :execrows
+-- Marks the user's unread posts in the feeds they follow as read, optionally
+-- only those of one feed or posted before a time.
+INSERT INTO post_states (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, now()
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text)
+AND (sqlc.narg(before)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(before)::timestamp)
+ON CONFLICT (user_id, post_id) DO UPDATE SET
+read_at = EXCLUDED.read_at
+WHERE post_states.read_at IS NULL;
+
+-- name: StarPost :exec
+INSERT INTO post_states (user_id, post_id, starred_at)
+VALUES ($1, $2, now())
+ON CONFLICT (user_id, post_id) DO UPDATE SET
+starred_at = COALESCE(post_states.starred_at, EXCLUDED.starred_at);
+
+-- name: UnstarPost :execrows
+UPDATE post_states SET starred_at = NULL
+WHERE user_id = $1 AND post_id = $2 AND starred_at IS NOT NULL;
+
+-- name: ListStarredPosts :many
+-- Lists the user's starred posts, most recently starred first. Posts of
+-- deleted feeds have an empty feed_name.
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.author,
+COALESCE(feeds.name, '') AS feed_name, post_states.read_at IS NOT NULL AS is_read
+FROM post_states
+JOIN posts ON posts.id = post_states.post_id
+LEFT JOIN feeds ON feeds.id = posts.feed_id
+WHERE post_states.user_id = $1 AND post_states.starred_at IS NOT NULL
+ORDER BY post_states.starred_at DESC;
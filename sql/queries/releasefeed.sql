@@ -0,0 +1,5 @@
+-- name: ReleaseFeed :exec
+UPDATE feeds
+SET claimed_by = NULL,
+claimed_until = NULL
+WHERE id = $1 AND claimed_by = $2;
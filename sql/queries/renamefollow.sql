@@ -0,0 +1,6 @@
+-- name: RenameFollow :execrows
+-- Sets the user's own title for a feed they follow, a null title goes back
+-- to the feed's name.
+UPDATE feed_follows SET title = sqlc.narg(title), updated_at = now()
+WHERE user_id = sqlc.arg(user_id)
+AND feed_id = (SELECT id FROM feeds WHERE url = sqlc.arg(feed_url));
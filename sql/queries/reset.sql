@@ -1,2 +1,163 @@
--- name: ResetTable :exec
-DELETE FROM users *;
\ No newline at end of file
+-- name: ResetAlertRules :one
+-- Deletes the user's own alert rules when by_user is set, and every rule
+-- scoped to the feeds they added when of_feeds is set.
+WITH deleted AS (
+    DELETE FROM alert_rules
+    WHERE (sqlc.arg(by_user)::bool
+        AND (sqlc.narg(user_id)::uuid IS NULL OR alert_rules.user_id = sqlc.narg(user_id)::uuid))
+    OR (sqlc.arg(of_feeds)::bool AND feed_id IN (
+        SELECT feeds.id FROM feeds
+        WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+    ))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetAlerts :one
+-- Deletes the alerts raised by posts in the feeds the user added when
+-- of_feeds is set, and the user's own alerts when by_user is set.
+WITH deleted AS (
+    DELETE FROM alerts
+    WHERE (sqlc.arg(of_feeds)::bool AND post_id IN (
+        SELECT posts.id FROM posts
+        LEFT JOIN feeds ON feeds.id = posts.feed_id
+        WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+    ))
+    OR (sqlc.arg(by_user)::bool
+        AND (sqlc.narg(user_id)::uuid IS NULL OR alerts.user_id = sqlc.narg(user_id)::uuid))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetFolders :one
+WITH deleted AS (
+    DELETE FROM folders
+    WHERE sqlc.narg(user_id)::uuid IS NULL OR folders.user_id = sqlc.narg(user_id)::uuid
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetMutes :one
+-- Deletes the user's own mute rules when by_user is set, and every rule
+-- scoped to the feeds they added when of_feeds is set.
+WITH deleted AS (
+    DELETE FROM mutes
+    WHERE (sqlc.arg(by_user)::bool
+        AND (sqlc.narg(user_id)::uuid IS NULL OR mutes.user_id = sqlc.narg(user_id)::uuid))
+    OR (sqlc.arg(of_feeds)::bool AND feed_id IN (
+        SELECT feeds.id FROM feeds
+        WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+    ))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetPostRevisions :one
+-- The Reset queries delete rows belonging to user_id, or every row when it
+-- is NULL, and return the deleted rows as a JSON array. Posts, feeds and
+-- their dependents belong to the user who added the feed; starred posts of
+-- deleted feeds belong to nobody and only go in a full reset.
+WITH deleted AS (
+    DELETE FROM post_revisions
+    WHERE post_id IN (
+        SELECT posts.id FROM posts
+        LEFT JOIN feeds ON feeds.id = posts.feed_id
+        WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+    )
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetPostStates :one
+-- Deletes the read state of posts in the feeds the user added when of_feeds
+-- is set, and the user's own read state when by_user is set.
+WITH deleted AS (
+    DELETE FROM post_states
+    WHERE (sqlc.arg(of_feeds)::bool AND post_id IN (
+        SELECT posts.id FROM posts
+        LEFT JOIN feeds ON feeds.id = posts.feed_id
+        WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+    ))
+    OR (sqlc.arg(by_user)::bool
+        AND (sqlc.narg(user_id)::uuid IS NULL OR post_states.user_id = sqlc.narg(user_id)::uuid))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetPosts :one
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE sqlc.narg(user_id)::uuid IS NULL OR feed_id IN (
+        SELECT feeds.id FROM feeds WHERE feeds.user_id = sqlc.narg(user_id)::uuid
+    )
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetFollowTags :one
+-- Deletes the tags of the follows ResetFeedFollows deletes with the same
+-- arguments.
+WITH deleted AS (
+    DELETE FROM follow_tags
+    WHERE follow_id IN (
+        SELECT feed_follows.id FROM feed_follows
+        WHERE (sqlc.arg(by_user)::bool
+            AND (sqlc.narg(user_id)::uuid IS NULL OR feed_follows.user_id = sqlc.narg(user_id)::uuid))
+        OR (sqlc.arg(of_feeds)::bool AND feed_id IN (
+            SELECT feeds.id FROM feeds
+            WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+        ))
+    )
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetFeedFollows :one
+-- Deletes the user's own follows when by_user is set, and every follow of
+-- the feeds they added when of_feeds is set.
+WITH deleted AS (
+    DELETE FROM feed_follows
+    WHERE (sqlc.arg(by_user)::bool
+        AND (sqlc.narg(user_id)::uuid IS NULL OR feed_follows.user_id = sqlc.narg(user_id)::uuid))
+    OR (sqlc.arg(of_feeds)::bool AND feed_id IN (
+        SELECT feeds.id FROM feeds
+        WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+    ))
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetFeedRetention :one
+WITH deleted AS (
+    DELETE FROM feed_retention
+    WHERE feed_id IN (
+        SELECT feeds.id FROM feeds
+        WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+    )
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetFeeds :one
+WITH deleted AS (
+    DELETE FROM feeds
+    WHERE sqlc.narg(user_id)::uuid IS NULL OR feeds.user_id = sqlc.narg(user_id)::uuid
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetSessions :one
+WITH deleted AS (
+    DELETE FROM sessions
+    WHERE sqlc.narg(user_id)::uuid IS NULL OR sessions.user_id = sqlc.narg(user_id)::uuid
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
+
+-- name: ResetUsers :one
+WITH deleted AS (
+    DELETE FROM users
+    WHERE sqlc.narg(user_id)::uuid IS NULL OR users.id = sqlc.narg(user_id)::uuid
+    RETURNING *
+)
+SELECT COALESCE(json_agg(deleted), '[]')::text AS deleted_rows FROM deleted;
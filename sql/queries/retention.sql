@@ -0,0 +1,117 @@
+-- name: SetFeedRetention :exec
+INSERT INTO feed_retention (feed_id, max_age_seconds, max_posts, keep_unread)
+VALUES($1, $2, $3, $4)
+ON CONFLICT (feed_id) DO UPDATE SET
+max_age_seconds = COALESCE(EXCLUDED.max_age_seconds, feed_retention.max_age_seconds),
+max_posts = COALESCE(EXCLUDED.max_posts, feed_retention.max_posts),
+keep_unread = COALESCE(EXCLUDED.keep_unread, feed_retention.keep_unread);
+
+-- name: DeleteFeedRetention :execrows
+DELETE FROM feed_retention WHERE feed_id = $1;
+
+-- name: GetDefaultRetention :one
+SELECT * FROM default_retention;
+
+-- name: SetDefaultRetention :one
+-- Changes the fields of the default retention policy that are not null.
+UPDATE default_retention SET
+max_age_seconds = COALESCE(sqlc.narg(max_age_seconds), max_age_seconds),
+max_posts = COALESCE(sqlc.narg(max_posts), max_posts),
+keep_unread = COALESCE(sqlc.narg(keep_unread), keep_unread)
+RETURNING *;
+
+-- name: DeleteOrphanedPosts :execrows
+-- Deletes posts whose feed was deleted once nobody has them starred.
+DELETE FROM posts
+WHERE feed_id IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+);
+
+-- name: ListOrphanedPosts :many
+-- Lists the posts DeleteOrphanedPosts would delete.
+SELECT posts.id, posts.title, COALESCE(posts.published_at, posts.created_at)::timestamp AS posted_at
+FROM posts
+WHERE feed_id IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+)
+ORDER BY posted_at;
+
+-- name: ListFeedRetention :many
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, feed_retention.max_age_seconds, feed_retention.max_posts, feed_retention.keep_unread
+FROM feed_retention
+JOIN feeds ON feeds.id = feed_retention.feed_id
+ORDER BY feeds.name;
+
+-- name: ListPrunablePosts :many
+-- Lists posts that fall outside their feed's retention policy. A feed
+-- without an override uses default_retention, zero means unlimited. Starred
+-- posts are always kept, and with keep_unread so are posts some follower
+-- hasn't read.
+WITH policy AS (
+    SELECT f.id AS feed_id,
+    COALESCE(r.max_age_seconds, d.max_age_seconds) AS max_age_seconds,
+    COALESCE(r.max_posts, d.max_posts) AS max_posts,
+    COALESCE(r.keep_unread, d.keep_unread) AS keep_unread
+    FROM feeds f
+    CROSS JOIN default_retention d
+    LEFT JOIN feed_retention r ON r.feed_id = f.id
+), ranked AS (
+    SELECT p.id, p.feed_id, COALESCE(p.published_at, p.created_at) AS posted_at,
+    row_number() OVER (PARTITION BY p.feed_id ORDER BY COALESCE(p.published_at, p.created_at) DESC) AS position
+    FROM posts p
+)
+SELECT posts.id, posts.title, feeds.name AS feed_name, ranked.posted_at::timestamp AS posted_at
+FROM ranked
+JOIN policy ON policy.feed_id = ranked.feed_id
+JOIN posts ON posts.id = ranked.id
+JOIN feeds ON feeds.id = ranked.feed_id
+WHERE ((policy.max_age_seconds > 0 AND ranked.posted_at < now() - make_interval(secs => policy.max_age_seconds))
+OR (policy.max_posts > 0 AND ranked.position > policy.max_posts))
+AND NOT (policy.keep_unread AND EXISTS (
+    SELECT 1 FROM feed_follows ff
+    LEFT JOIN post_states ps ON ps.post_id = ranked.id AND ps.user_id = ff.user_id
+    WHERE ff.feed_id = ranked.feed_id AND ps.read_at IS NULL
+))
+AND NOT EXISTS (
+    SELECT 1 FROM post_states st
+    WHERE st.post_id = ranked.id AND st.starred_at IS NOT NULL
+)
+ORDER BY feeds.name, ranked.posted_at;
+
+-- name: PrunePosts :many
+-- Deletes posts that fall outside their feed's retention policy and returns
+-- the feed name of each deleted post.
+WITH policy AS (
+    SELECT f.id AS feed_id,
+    COALESCE(r.max_age_seconds, d.max_age_seconds) AS max_age_seconds,
+    COALESCE(r.max_posts, d.max_posts) AS max_posts,
+    COALESCE(r.keep_unread, d.keep_unread) AS keep_unread
+    FROM feeds f
+    CROSS JOIN default_retention d
+    LEFT JOIN feed_retention r ON r.feed_id = f.id
+), ranked AS (
+    SELECT p.id, p.feed_id, COALESCE(p.published_at, p.created_at) AS posted_at,
+    row_number() OVER (PARTITION BY p.feed_id ORDER BY COALESCE(p.published_at, p.created_at) DESC) AS position
+    FROM posts p
+)
+DELETE FROM posts
+USING ranked, policy, feeds
+WHERE posts.id = ranked.id
+AND policy.feed_id = ranked.feed_id
+AND feeds.id = ranked.feed_id
+AND ((policy.max_age_seconds > 0 AND ranked.posted_at < now() - make_interval(secs => policy.max_age_seconds))
+OR (policy.max_posts > 0 AND ranked.position > policy.max_posts))
+AND NOT (policy.keep_unread AND EXISTS (
+    SELECT 1 FROM feed_follows ff
+    LEFT JOIN post_states ps ON ps.post_id = ranked.id AND ps.user_id = ff.user_id
+    WHERE ff.feed_id = ranked.feed_id AND ps.read_at IS NULL
+))
+AND NOT EXISTS (
+    SELECT 1 FROM post_states st
+    WHERE st.post_id = ranked.id AND st.starred_at IS NOT NULL
+)
+RETURNING feeds.name AS feed_name;
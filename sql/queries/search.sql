@@ -0,0 +1,24 @@
+-- name: SearchPosts :many
+-- Finds posts matching a web search style query ("phrases", -exclusions,
+-- or) in the feeds the user follows, or in every feed with all_feeds.
+-- Snippets mark matches with \x02 and \x03.
+SELECT posts.id, posts.title, posts.url, posts.published_at, feeds.name AS feed_name,
+ts_rank(posts.search, query)::float8 AS rank,
+ts_headline('english',
+    COALESCE(posts.content, posts.description, posts.title),
+    query,
+    'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=20, MinWords=8'
+)::text AS snippet
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)::text) AS query
+WHERE posts.search @@ query
+AND (sqlc.arg(all_feeds)::bool OR EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg(user_id)::uuid
+))
+AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url)::text)
+AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until)::timestamp)
+ORDER BY rank DESC, COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT sqlc.arg(page_size);
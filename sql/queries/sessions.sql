@@ -0,0 +1,23 @@
+-- name: CreateSession :one
+INSERT INTO sessions (user_id, token_hash, expires_at)
+VALUES(
+    sqlc.arg(user_id),
+    sqlc.arg(token_hash),
+    now() + make_interval(secs => sqlc.arg(ttl_seconds)::float8)
+)
+RETURNING *;
+
+-- name: GetSessionUser :one
+SELECT users.* FROM sessions
+JOIN users ON users.id = sessions.user_id
+WHERE sessions.token_hash = $1
+AND sessions.revoked_at IS NULL
+AND sessions.expires_at > now();
+
+-- name: RevokeSession :exec
+UPDATE sessions SET revoked_at = now()
+WHERE token_hash = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :execrows
+UPDATE sessions SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,31 @@
+-- name: AddFollowTag :one
+-- Tags the user's follow of a feed and reports whether they follow it.
+-- Adding a tag the follow already has changes nothing.
+WITH follow AS (
+    SELECT feed_follows.id FROM feed_follows
+    JOIN feeds ON feeds.id = feed_follows.feed_id
+    WHERE feed_follows.user_id = sqlc.arg(user_id) AND feeds.url = sqlc.arg(feed_url)
+), inserted AS (
+    INSERT INTO follow_tags (follow_id, tag)
+    SELECT follow.id, sqlc.arg(tag)::text FROM follow
+    ON CONFLICT DO NOTHING
+)
+SELECT EXISTS (SELECT 1 FROM follow) AS followed;
+
+-- name: ListTags :many
+-- Lists the tags on the user's follows with the number of feeds tagged.
+SELECT follow_tags.tag, count(*) AS feeds
+FROM follow_tags
+JOIN feed_follows ON feed_follows.id = follow_tags.follow_id
+WHERE feed_follows.user_id = $1
+GROUP BY follow_tags.tag
+ORDER BY follow_tags.tag;
+
+-- name: RemoveFollowTag :execrows
+DELETE FROM follow_tags
+USING feed_follows, feeds
+WHERE follow_tags.follow_id = feed_follows.id
+AND feeds.id = feed_follows.feed_id
+AND feed_follows.user_id = sqlc.arg(user_id)
+AND feeds.url = sqlc.arg(feed_url)
+AND follow_tags.tag = sqlc.arg(tag);
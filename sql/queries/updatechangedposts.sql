@@ -0,0 +1,42 @@
+-- name: UpdateChangedPosts :many
+-- Saves the stored version of every post in the batch whose title,
+-- description or content differ to post_revisions, then updates it.
+-- Content appearing on a post stored without any, as on the first scrape
+-- after content was added to posts, is filled in without a revision.
+-- Returns the URLs that were revised.
+WITH incoming AS (
+    SELECT url, title, description, content FROM unnest(
+        sqlc.arg(urls)::text[],
+        sqlc.arg(titles)::text[],
+        sqlc.arg(descriptions)::text[],
+        sqlc.arg(contents)::text[]
+    ) AS i(url, title, description, content)
+), changed AS (
+    SELECT p.id, p.url, p.title, p.description, p.content, i.title AS new_title, i.description AS new_description, NULLIF(i.content, '') AS new_content,
+    (p.title IS DISTINCT FROM i.title
+    OR p.description IS DISTINCT FROM i.description
+    OR (p.content IS NOT NULL AND p.content IS DISTINCT FROM NULLIF(i.content, ''))) AS revised
+    FROM posts p
+    JOIN incoming i ON p.url = i.url
+    WHERE p.feed_id = sqlc.arg(feed_id)::uuid
+    AND (p.title IS DISTINCT FROM i.title
+    OR p.description IS DISTINCT FROM i.description
+    OR p.content IS DISTINCT FROM NULLIF(i.content, ''))
+    FOR UPDATE OF p
+), revision AS (
+    INSERT INTO post_revisions (post_id, title, description, content)
+    SELECT changed.id, changed.title, changed.description, changed.content FROM changed
+    WHERE changed.revised
+    RETURNING post_id
+), updated AS (
+    UPDATE posts
+    SET title = changed.new_title,
+    description = changed.new_description,
+    content = changed.new_content,
+    updated_at = CASE WHEN changed.revised THEN now() ELSE posts.updated_at END
+    FROM changed
+    WHERE posts.id = changed.id
+    RETURNING posts.id
+)
+SELECT changed.url FROM changed
+WHERE changed.revised;
@@ -1,9 +1,59 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+-- The first user of a new database is made an admin.
+INSERT INTO users (id, created_at, updated_at, name, password_hash, is_admin)
 VALUES(
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    NOT EXISTS (SELECT 1 FROM users)
 )
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2,
+updated_at = now()
+WHERE id = $1;
+
+-- name: GetUserInfo :one
+SELECT users.id, users.created_at, users.name,
+users.password_hash IS NOT NULL AS has_password,
+users.is_admin,
+(SELECT count(*) FROM feed_follows WHERE feed_follows.user_id = users.id) AS follows,
+(SELECT count(*) FROM feeds WHERE feeds.user_id = users.id) AS feeds_added,
+(SELECT count(*) FROM sessions WHERE sessions.user_id = users.id
+    AND sessions.revoked_at IS NULL AND sessions.expires_at > now()) AS active_sessions
+FROM users
+WHERE users.name = $1;
+
+-- name: ListUserFeeds :many
+-- Lists the feeds a user added, which are deleted along with them, and how
+-- many other users follow each.
+SELECT feeds.name, feeds.url,
+(SELECT count(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id <> feeds.user_id) AS other_followers,
+(SELECT count(*) FROM posts WHERE posts.feed_id = feeds.id) AS posts
+FROM feeds
+WHERE feeds.user_id = $1
+ORDER BY feeds.name;
+
+-- name: RenameUser :one
+UPDATE users
+SET name = $2,
+updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
+-- name: CountAdmins :one
+SELECT count(*) FROM users WHERE is_admin;
+
+-- name: SetUserAdmin :exec
+UPDATE users
+SET is_admin = $2,
+updated_at = now()
+WHERE id = $1;
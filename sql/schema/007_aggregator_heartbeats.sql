@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE aggregator_heartbeats(
+instance TEXT PRIMARY KEY,
+started_at TIMESTAMP NOT NULL DEFAULT now(),
+last_seen_at TIMESTAMP NOT NULL DEFAULT now(),
+last_success_at TIMESTAMP,
+stopped_at TIMESTAMP,
+interval_seconds DOUBLE PRECISION NOT NULL,
+feeds_fetched INTEGER NOT NULL DEFAULT 0,
+feeds_failed INTEGER NOT NULL DEFAULT 0
+);
+
+-- +goose Down
+DROP TABLE aggregator_heartbeats;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE feed_retention(
+feed_id UUID PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+max_age_seconds DOUBLE PRECISION,
+max_posts INTEGER
+);
+
+-- +goose Down
+DROP TABLE feed_retention;
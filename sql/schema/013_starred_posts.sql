@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE post_states ADD COLUMN starred_at TIMESTAMP;
+
+CREATE INDEX post_states_starred_idx ON post_states(user_id, starred_at) WHERE starred_at IS NOT NULL;
+
+-- starred posts outlive their feed, they are kept with no feed_id
+ALTER TABLE posts ALTER COLUMN feed_id DROP NOT NULL;
+ALTER TABLE posts DROP CONSTRAINT posts_feed_id_fkey;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_fkey
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE SET NULL;
+
+-- +goose StatementBegin
+CREATE FUNCTION delete_unstarred_feed_posts() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM posts
+    WHERE feed_id = OLD.id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+    );
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER feeds_delete_unstarred_posts
+BEFORE DELETE ON feeds
+FOR EACH ROW EXECUTE FUNCTION delete_unstarred_feed_posts();
+
+-- +goose Down
+DROP TRIGGER feeds_delete_unstarred_posts ON feeds;
+DROP FUNCTION delete_unstarred_feed_posts();
+DELETE FROM posts WHERE feed_id IS NULL;
+ALTER TABLE posts DROP CONSTRAINT posts_feed_id_fkey;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_fkey
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE;
+ALTER TABLE posts ALTER COLUMN feed_id SET NOT NULL;
+DROP INDEX post_states_starred_idx;
+ALTER TABLE post_states DROP COLUMN starred_at;
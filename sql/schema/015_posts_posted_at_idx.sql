@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX posts_posted_at_idx ON posts ((COALESCE(published_at, created_at)), id);
+
+-- +goose Down
+DROP INDEX posts_posted_at_idx;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE folders(
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+name TEXT NOT NULL,
+UNIQUE (user_id, name)
+);
+
+ALTER TABLE feed_follows ADD COLUMN folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
+CREATE INDEX feed_follows_folder_id_idx ON feed_follows(folder_id);
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN folder_id;
+DROP TABLE folders;
@@ -0,0 +1,51 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author TEXT;
+
+-- +goose StatementBegin
+-- rule_matches reports whether a mute or alert rule matches a post. field
+-- is title, description, author, url or any; patterns are matched case
+-- insensitively, as a substring or a POSIX regular expression.
+CREATE FUNCTION rule_matches(rule_field TEXT, rule_pattern TEXT, rule_is_regex BOOLEAN,
+    post_title TEXT, post_description TEXT, post_author TEXT, post_url TEXT) RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM unnest(CASE rule_field
+            WHEN 'title' THEN ARRAY[post_title]
+            WHEN 'description' THEN ARRAY[post_description]
+            WHEN 'author' THEN ARRAY[post_author]
+            WHEN 'url' THEN ARRAY[post_url]
+            ELSE ARRAY[post_title, post_description, post_author, post_url]
+        END) AS value
+        WHERE CASE WHEN rule_is_regex THEN value ~* rule_pattern
+            ELSE strpos(lower(value), lower(rule_pattern)) > 0 END
+    )
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+CREATE TABLE mutes(
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+field TEXT NOT NULL CHECK (field IN ('any', 'title', 'description', 'author', 'url')),
+pattern TEXT NOT NULL,
+is_regex BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX mutes_user_id_idx ON mutes(user_id);
+
+-- post_is_muted reports whether any of the user's mute rules hides a post
+CREATE FUNCTION post_is_muted(mute_user_id UUID, post posts) RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM mutes
+        WHERE mutes.user_id = mute_user_id
+        AND (mutes.feed_id IS NULL OR mutes.feed_id = post.feed_id)
+        AND rule_matches(mutes.field, mutes.pattern, mutes.is_regex,
+            post.title, post.description, post.author, post.url)
+    )
+$$ LANGUAGE sql STABLE;
+
+-- +goose Down
+DROP FUNCTION post_is_muted(UUID, posts);
+DROP TABLE mutes;
+DROP FUNCTION rule_matches(TEXT, TEXT, BOOLEAN, TEXT, TEXT, TEXT, TEXT);
+ALTER TABLE posts DROP COLUMN author;
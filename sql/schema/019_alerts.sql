@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE alert_rules(
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+field TEXT NOT NULL CHECK (field IN ('any', 'title', 'description', 'author', 'url')),
+pattern TEXT NOT NULL,
+is_regex BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX alert_rules_user_id_idx ON alert_rules(user_id);
+
+CREATE TABLE alerts(
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+rule_id UUID NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+seen_at TIMESTAMP,
+UNIQUE (rule_id, post_id)
+);
+
+CREATE INDEX alerts_user_id_idx ON alerts(user_id, created_at);
+CREATE INDEX alerts_post_id_idx ON alerts(post_id);
+
+-- +goose Down
+DROP TABLE alerts;
+DROP TABLE alert_rules;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE aggregator_heartbeats ADD COLUMN once BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE aggregator_heartbeats DROP COLUMN once;
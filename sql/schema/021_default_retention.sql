@@ -0,0 +1,14 @@
+-- +goose Up
+-- default_retention holds the one retention policy used by feeds without
+-- an override in feed_retention. Zero means unlimited.
+CREATE TABLE default_retention(
+id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+max_age_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+max_posts INTEGER NOT NULL DEFAULT 0,
+keep_unread BOOLEAN NOT NULL DEFAULT false
+);
+
+INSERT INTO default_retention DEFAULT VALUES;
+
+-- +goose Down
+DROP TABLE default_retention;
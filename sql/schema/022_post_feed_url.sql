@@ -0,0 +1,11 @@
+-- +goose Up
+-- feed_url is the URL of the feed a post was collected from, kept after the
+-- feed is deleted so only a feed with the same URL adopts the post again.
+ALTER TABLE posts ADD COLUMN feed_url TEXT;
+
+UPDATE posts SET feed_url = feeds.url
+FROM feeds
+WHERE feeds.id = posts.feed_id;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN feed_url;
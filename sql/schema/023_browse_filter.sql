@@ -0,0 +1,18 @@
+-- +goose Up
+-- browse_filter reports whether a post passes the filters of browse. follow
+-- is the user's follow of the post's feed and state their post_states row
+-- for the post, NULL when they have none.
+CREATE FUNCTION browse_filter(follow feed_follows, post posts, state post_states,
+    unread_only BOOLEAN, only_feed_url TEXT, only_folder TEXT, posted_since TIMESTAMP, posted_until TIMESTAMP)
+RETURNS BOOLEAN AS $$
+    SELECT (NOT unread_only OR state.read_at IS NULL)
+    AND (only_feed_url IS NULL OR post.feed_id = (SELECT feeds.id FROM feeds WHERE feeds.url = only_feed_url))
+    AND (only_folder IS NULL OR follow.folder_id IN (
+        SELECT folders.id FROM folders
+        WHERE folders.user_id = follow.user_id AND folders.name = only_folder))
+    AND (posted_since IS NULL OR COALESCE(post.published_at, post.created_at) >= posted_since)
+    AND (posted_until IS NULL OR COALESCE(post.published_at, post.created_at) < posted_until)
+$$ LANGUAGE sql STABLE;
+
+-- +goose Down
+DROP FUNCTION browse_filter(feed_follows, posts, post_states, BOOLEAN, TEXT, TEXT, TIMESTAMP, TIMESTAMP);
@@ -0,0 +1,9 @@
+-- +goose Up
+-- last_attempt_at is when a feed was last claimed for fetching, whether or
+-- not the fetch succeeded, so a failing feed waits its turn like the others.
+ALTER TABLE feeds ADD COLUMN last_attempt_at TIMESTAMP;
+
+UPDATE feeds SET last_attempt_at = last_fetched_at;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN last_attempt_at;